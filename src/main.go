@@ -7,205 +7,261 @@ import (
 	_ "log"
 	_ "math"
 	"os"
-	"os/signal"
 	. "pong"
 	. "pong/draw"
 	"runtime"
 	"runtime/pprof"
+	"strings"
 	"time"
 )
 
 var cpuProfile = flag.String("cpuprofile", "", "write cpu profile to file")
 var webDisplay = flag.Bool("webdisplay", false, "use webhost on localhost:8080 for the display")
+var terminalDisplay = flag.Bool("terminaldisplay", false, "draw the field in the terminal with ANSI colors")
+var netDisplay = flag.String("netdisplay", "", "send frames over the network with opc, e131 or artnet instead of SPI")
+var recordDir = flag.String("record", "", "directory to save recordings in, SIGUSR1 starts and stops a recording")
+var recordGames = flag.Bool("recordgames", false, "record every game, needs -record")
+var recordFormats = flag.String("recordformats", "gif,raw", "comma separated formats recordings are saved as: gif, apng, raw")
+var recordScale = flag.Int("recordscale", 8, "width in pixels of each led in recorded images")
+var recordRowHeight = flag.Int("recordrowheight", 8, "height in pixels of recorded images")
+var replayFrames = flag.String("replayframes", "", "play a raw frame log on the display and exit")
+var replayDir = flag.String("replaydir", "", "directory to save a replay of every match in")
+var statsFile = flag.String("stats", "../stats.jsonl", "file every match is added to for the leaderboard, served at /stats by the web display, -stats \"\" keeps none")
+var replayFile = flag.String("replay", "", "play a saved match, hold the left button to rewind and the right one to fast forward")
+var keyboardInput = flag.Bool("keyboard", false, "play with the keyboard, LeftKeys and RightKeys in settings.xml, default a and l, and s and k for the inner players in doubles")
+var calibrate = flag.Bool("calibrate", false, "show test patterns to tune GammaExponent, ColorOrder and the gains, settings.xml is reloaded when saved")
 
 // Application entry point
 func main() {
 
 	Settings.Read()
+	if _, err := ParsePowerUpKinds(Settings.PowerUps); err != nil {
+		log.Fatal(err)
+	}
 
 	flag.Parse()
+
+	// restore the terminal when the game or a replay ends
+	defer Cleanup()
+
+	var replay *GameReplay
+	if *replayFile != "" {
+		replay = loadReplay(*replayFile)
+	}
+
 	if *cpuProfile != "" {
 		f, err := os.Create(*cpuProfile)
 		if err != nil {
 			panic(err)
 		}
 		pprof.StartCPUProfile(f)
-		//defer pprof.StopCPUProfile() // line not executed because of how exiting from http.ListenAnServe is a process kill
 
-		// capture ctrl+c and stop CPU profiler
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt)
-		go func() {
-			for sig := range c {
-				fmt.Printf("captured %v, stopping profiler and exiting..", sig)
-				pprof.StopCPUProfile()
-				os.Exit(1)
-			}
-		}()
+		// stop the profiler on ctrl+c along with restoring the terminal, or when the game ends
+		AtExit(pprof.StopCPUProfile)
 
 		fmt.Println("Start profiling")
 	}
 
 	log.Print("MinFrameTime is ", Settings.MinFrameTime)
 
+	if *netDisplay != "" {
+		Settings.NetworkProtocol = *netDisplay
+	}
+
 	var display Display
-	if *webDisplay || runtime.GOOS == "windows" {
+	if !*terminalDisplay && !*webDisplay && *netDisplay == "" && len(Settings.Outputs) > 0 {
+		multiDisplay := NewMultiDisplay()
+		for _, output := range Settings.Outputs {
+			log.Print("Adding output ", output.Type)
+			multiDisplay.Add(NewOutputDisplay(output, Settings), output.MaxFPS)
+		}
+		display = multiDisplay
+	} else if *terminalDisplay {
+		display = NewTerminalDisplay(Settings)
+	} else if *webDisplay {
+		display = NewWebDisplay(Settings)
+	} else if Settings.NetworkProtocol != "" {
+		display = MapToMatrix(NewNetworkDisplay(Settings), Settings)
+	} else if runtime.GOOS == "windows" {
 		display = NewWebDisplay(Settings)
 	} else {
-		display = NewLedDisplay(Settings)
+		display = MapToMatrix(NewLedDisplay(Settings), Settings)
 	}
 
-	buttons := NewGpioReader(Settings)
-
-	// should intro and game(play / dead / win) be different states in statemachine?
-
-	// loop forever
-	for {
-		runIntro(buttons, display)
-		runOpening(display)
-		winner, bounces := runGame(buttons, display)
-		go PlayTTS(fmt.Sprint("Game over. Score ", bounces))
-		runClosing(buttons, display, winner)
+	if Settings.LimitsPower() {
+		powerLimiter := NewPowerLimiter(display, Settings)
+		display = powerLimiter
+		go logPowerEstimate(powerLimiter)
 	}
-}
-
-// Run an intro animation
-func runIntro(buttons *GpioReader, display Display) {
 
-	if runtime.GOOS == "windows" {
+	if *replayFrames != "" {
+		replayFrameLog(*replayFrames, display)
 		return
 	}
 
-	field := NewGameField(Settings.LedCount)
-	field.Add(NewSinusoid(field, 1))
+	var recorder *RecordingDisplay
+	if *recordDir != "" {
+		recorder = NewRecordingDisplay(display, Settings)
+		display = recorder
 
-	curTime := time.Now()
-	prevTime := curTime
-
-	ticks := time.NewTicker(time.Duration(Settings.MinFrameTime*1000.0) * time.Millisecond)
-	defer ticks.Stop()
-
-	for _ = range ticks.C {
-
-		prevTime, curTime = curTime, time.Now()
-		dt := curTime.Sub(prevTime).Seconds()
-
-		if buttons.LeftButton() || buttons.RightButton() {
-			return
-		}
-
-		field.Animate(dt)
-		field.RenderTo(display)
+		// SIGUSR1 starts a recording, the next one saves it
+		toggles := make(chan os.Signal, 1)
+		NotifyRecordToggle(toggles)
+		go func() {
+			for _ = range toggles {
+				if recorder.IsRecording() {
+					saveRecording(recorder)
+				} else {
+					log.Print("Recording started")
+					recorder.StartRecording()
+				}
+			}
+		}()
 	}
-}
-
-// Run an animation to start the game
-func runOpening(display Display) {
 
-	field := NewGameField(Settings.LedCount)
-	countDown := NewCountdown(field, 2)
-	field.Add(countDown)
-
-	go PlaySound(GAMESTART)
-
-	curTime := time.Now()
-	prevTime := curTime
+	game := &session{
+		display:  display,
+		buttons:  newInput(display),
+		recorder: recorder,
+	}
 
-	ticks := time.NewTicker(time.Duration(Settings.MinFrameTime*1000.0) * time.Millisecond)
-	defer ticks.Stop()
+	game.leftProfile, _ = Settings.Profile(Settings.LeftProfile)
+	game.rightProfile, _ = Settings.Profile(Settings.RightProfile)
 
-	for _ = range ticks.C {
+	runner := NewSceneRunner(display, game.buttons, time.Duration(Settings.MinFrameTime*1000.0)*time.Millisecond,
+		time.Duration(Settings.SimulationStep*float64(time.Second)))
 
-		prevTime, curTime = curTime, time.Now()
-		dt := curTime.Sub(prevTime).Seconds()
+	if replay != nil {
+		game.runner = runner
+		runner.Add(sceneReplay, &replayScene{game: game, replay: replay})
+		runner.Run(sceneReplay)
+		return
+	}
 
-		field.Animate(dt)
+	if *calibrate {
+		runner.Add(sceneCalibration, &calibrationScene{game: game})
+		runner.Run(sceneCalibration)
+		return
+	}
 
-		if countDown.TimeRemaining() <= 0 {
-			return
+	if *statsFile != "" {
+		store, err := OpenStatsStore(*statsFile)
+		if err != nil {
+			log.Fatal(err)
 		}
-
-		field.RenderTo(display)
+		store.HandleHttp()
+		game.statsStore = store
 	}
+
+	addGameScenes(runner, game)
+	runner.Run(sceneIntro)
 }
 
-// Run the actual game
-func runGame(buttons *GpioReader, display Display) (leftPlayerWon bool, totalBounces int) {
+// Merge the inputs from settings and the command line with the buttons of the displays
+func newInput(display Display) InputSource {
 
-	field := NewGameField(64)
+	var inputs []InputSource
+	for _, input := range Settings.Inputs {
+		log.Print("Adding input ", input.Type)
+		inputs = append(inputs, NewInput(input, Settings))
+	}
+	if len(Settings.Inputs) == 0 && runtime.GOOS != "windows" {
+		inputs = append(inputs, NewGpioReader(Settings))
+	}
+	if *keyboardInput {
+		inputs = append(inputs, NewKeyboardInput(Settings))
+	}
+	inputs = append(inputs, DisplayInputs(display)...)
 
-	ball := NewBall(field)
-	field.Add(ball)
+	if len(inputs) == 0 {
+		log.Print("No other inputs, using the keyboard")
+		inputs = append(inputs, NewKeyboardInput(Settings))
+	}
 
-	leftPlayer := NewPlayer(true, Settings.LifeInSeconds, field)
-	field.Add(leftPlayer)
-	rightPlayer := NewPlayer(false, Settings.LifeInSeconds, field)
-	field.Add(rightPlayer)
+	return MergeInputs(inputs...)
+}
 
-	curTime := time.Now()
-	prevTime := curTime
-	totalBounces = 0
+// Read a saved match and use the settings it was played with
+func loadReplay(path string) *GameReplay {
 
-	ticks := time.NewTicker(time.Duration(Settings.MinFrameTime*1000.0) * time.Millisecond)
-	defer ticks.Stop()
+	replay, err := LoadGameReplay(path)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	for _ = range ticks.C {
+	if replay.Width != Settings.FieldWidth() || replay.Height != Settings.FieldHeight() {
+		log.Fatalf("The replay was played on a %dx%d field, the leds are %dx%d", replay.Width, replay.Height, Settings.FieldWidth(), Settings.FieldHeight())
+	}
 
-		prevTime, curTime = curTime, time.Now()
-		dt := curTime.Sub(prevTime).Seconds()
+	Settings.SimulationHz = replay.SimulationHz
+	Settings.SimulationStep = 1.0 / replay.SimulationHz
+	Settings.LifeInSeconds = replay.LifeInSeconds
+	Settings.BounceVelocityIncrease = replay.BounceVelocityIncrease
+	Settings.MatchBestOf = replay.MatchBestOf
+	Settings.Doubles = replay.Doubles
+	if replay.Doubles {
+		Settings.InnerPaddleDepth = replay.InnerPaddleDepth
+	}
+	Settings.PowerUpSeconds = replay.PowerUpSeconds
+	Settings.PowerUps = replay.PowerUps
+	Settings.Shots = replay.Shots
 
-		leftPlayer.UpdatePaddleActive(buttons.LeftButton())
-		rightPlayer.UpdatePaddleActive(buttons.RightButton())
-		//leftPlayer.UpdatePaddleActive(true)
-		//rightPlayer.UpdatePaddleActive(true)
+	return replay
+}
 
-		field.Animate(dt)
+// Stop the recording and save it in the background
+func saveRecording(recorder *RecordingDisplay) {
 
-		ball.UpdateOffensiveHide(leftPlayer, rightPlayer)
+	frames := recorder.StopRecording()
+	name := "pongpi-" + time.Now().Format("20060102-150405")
+	options := RecordingImageOptions{Scale: *recordScale, RowHeight: *recordRowHeight}
+	if Settings.FieldHeight() > 1 {
+		options.Columns = Settings.FieldWidth()
+	}
 
-		playerMissed, bounce := ball.MissedByPlayer(leftPlayer, rightPlayer, Settings.BounceVelocityIncrease)
-		if playerMissed != nil {
-			ball.ResetPosition(field)
-			if playerMissed.DecreaseLife(0.75) {
-				return playerMissed == leftPlayer, totalBounces
-			}
-		}
-		if bounce {
-			totalBounces++
+	go func() {
+		if err := SaveRecording(*recordDir, name, frames, strings.Split(*recordFormats, ","), options); err != nil {
+			log.Print("Failed to save recording ", err)
+			return
 		}
+		log.Print("Saved recording ", name, " with ", len(frames), " frames")
+	}()
+}
 
-		field.RenderTo(display)
+// Periodically log how much current the leds are estimated to draw
+func logPowerEstimate(powerLimiter *PowerLimiter) {
+	for _ = range time.Tick(10 * time.Second) {
+		requested, limited := powerLimiter.EstimatedMilliamps()
+		log.Printf("Estimated current %.0fmA, limited to %.0fmA at brightness %.2f", requested, limited, powerLimiter.Scale())
 	}
-
-	panic("Shouldn't get here")
 }
 
-// Run an animation showing the winner
-func runClosing(buttons *GpioReader, display Display, winner bool) {
-
-	field := NewGameField(Settings.LedCount)
-	winnerDisplay := NewWinner(field, winner, 4)
-	field.Add(winnerDisplay)
-
-	//go PlaySound(GAMEOVER)
+// Play a raw frame log on the display
+func replayFrameLog(path string, display Display) {
 
-	curTime := time.Now()
-	prevTime := curTime
-
-	ticks := time.NewTicker(time.Duration(Settings.MinFrameTime*1000.0) * time.Millisecond)
-	defer ticks.Stop()
-
-	for _ = range ticks.C {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
 
-		prevTime, curTime = curTime, time.Now()
-		dt := curTime.Sub(prevTime).Seconds()
+	frames, err := ReadFrameLog(file)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		field.Animate(dt)
+	log.Print("Replaying ", len(frames), " frames from ", path)
+	ReplayFrames(frames, display)
+}
 
-		if winnerDisplay.TimeRemaining() <= 0 {
-			return
-		}
+// Create a field the size of the display
+func newField() *GameField {
+	return NewGameField2D(Settings.FieldWidth(), Settings.FieldHeight())
+}
 
-		field.RenderTo(display)
+// Show the game phase on displays that support it
+func setStatus(display Display, status string) {
+	if statusDisplay, ok := display.(StatusDisplay); ok {
+		statusDisplay.SetStatus(status)
 	}
 }
@@ -0,0 +1,141 @@
+package pong
+
+import (
+	"sync"
+	"time"
+)
+
+// Identifies one of the buttons
+type ButtonId int
+
+const (
+	LeftButtonId ButtonId = iota
+	RightButtonId
+
+	// buttons of the second player on each side in doubles, who plays the inner paddle
+	LeftInnerButtonId
+	RightInnerButtonId
+)
+
+// number of buttons, the inner ones are only used in doubles
+const ButtonCount = 4
+
+// True for the buttons of the players on the left side
+func (button ButtonId) IsLeft() bool {
+	return button == LeftButtonId || button == LeftInnerButtonId
+}
+
+type ButtonEvent int
+
+const (
+	NoEvent ButtonEvent = iota
+	ButtonPush
+	ButtonRelease
+)
+
+// Ways of reading the buttons, used in SettingsData.GpioBackend
+const (
+	GpioSysfs   = "sysfs"   // /sys/class/gpio value files, exported with wiringPi's gpio tool when missing
+	GpioChardev = "chardev" // the /dev/gpiochipN character device
+)
+
+// A button changing state at a point in time
+type ButtonPress struct {
+	Button ButtonId
+	Event  ButtonEvent
+	Time   time.Time
+}
+
+// debounce used when ButtonDebounceMs isn't set
+const defaultButtonDebounce = 5 * time.Millisecond
+
+// events that can wait to be read before newer ones are dropped
+const buttonEventBuffer = 64
+
+// Debounces raw button readings into ButtonPress events
+type buttonEvents struct {
+
+	// changes within this long of the previous change of a button are ignored
+	debounce time.Duration
+
+	lock sync.Mutex
+
+	// debounced state of each button and the time it last changed
+	down       [ButtonCount]bool
+	lastChange [ButtonCount]time.Time
+
+	events chan ButtonPress
+}
+
+// Construct buttonEvents with the debounce time from settings
+func newButtonEvents(settings SettingsData) *buttonEvents {
+
+	debounce := defaultButtonDebounce
+	if settings.ButtonDebounceMs > 0 {
+		debounce = time.Duration(settings.ButtonDebounceMs * float64(time.Millisecond))
+	} else if settings.ButtonDebounceMs < 0 {
+		debounce = 0
+	}
+
+	return &buttonEvents{
+		debounce: debounce,
+		events:   make(chan ButtonPress, buttonEventBuffer),
+	}
+}
+
+// Record a raw reading of button taken at time at, returns false if a change was ignored because it came too soon
+func (this *buttonEvents) update(button ButtonId, down bool, at time.Time) (settled bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if down == this.down[button] {
+		return true
+	}
+	if at.Sub(this.lastChange[button]) < this.debounce {
+		return false
+	}
+
+	this.down[button] = down
+	this.lastChange[button] = at
+
+	press := ButtonPress{Button: button, Event: ButtonRelease, Time: at}
+	if down {
+		press.Event = ButtonPush
+	}
+
+	// never block the reader, the debounced state stays correct when nobody is reading events
+	select {
+	case this.events <- press:
+	default:
+	}
+
+	return true
+}
+
+// Debounced state of button
+func (this *buttonEvents) isDown(button ButtonId) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	return this.down[button]
+}
+
+// Get state of any button
+func (this *buttonEvents) ButtonDown(button ButtonId) bool {
+	return this.isDown(button)
+}
+
+// Debounced push and release events of every button
+func (this *buttonEvents) Events() <-chan ButtonPress {
+	return this.events
+}
+
+// Get state of the left button
+func (this *buttonEvents) LeftButton() bool {
+	return this.isDown(LeftButtonId)
+}
+
+// Get state of the right button
+func (this *buttonEvents) RightButton() bool {
+	return this.isDown(RightButtonId)
+}
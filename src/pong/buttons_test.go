@@ -0,0 +1,54 @@
+package pong
+
+import (
+	"testing"
+	"time"
+)
+
+// Events waiting on the channel
+func receivedPresses(events <-chan ButtonPress) (presses []ButtonPress) {
+	for {
+		select {
+		case press := <-events:
+			presses = append(presses, press)
+		default:
+			return
+		}
+	}
+}
+
+func Test_ButtonEvents_Debounce(t *testing.T) {
+	buttons := newButtonEvents(SettingsData{ButtonDebounceMs: 5})
+	start := time.Now()
+
+	Assert(boolToInt(buttons.update(LeftButtonId, true, start)), 1, "push settled", t)
+	Assert(boolToInt(buttons.update(LeftButtonId, false, start.Add(time.Millisecond))), 0, "bounce ignored", t)
+	Assert(boolToInt(buttons.update(LeftButtonId, true, start.Add(2*time.Millisecond))), 1, "same state settled", t)
+	Assert(boolToInt(buttons.update(LeftButtonId, false, start.Add(20*time.Millisecond))), 1, "release settled", t)
+	buttons.update(RightButtonId, true, start.Add(21*time.Millisecond))
+
+	presses := receivedPresses(buttons.events)
+	Assert(len(presses), 3, "presses", t)
+	Assert(int(presses[0].Event), int(ButtonPush), "first push", t)
+	Assert(int(presses[1].Event), int(ButtonRelease), "then release", t)
+	Assert(int(presses[1].Time.Sub(start)/time.Millisecond), 20, "release time", t)
+	Assert(int(presses[2].Button), int(RightButtonId), "right button", t)
+	Assert(boolToInt(buttons.isDown(RightButtonId)), 1, "right down", t)
+}
+
+func Test_ButtonEvents_DebounceOff(t *testing.T) {
+	buttons := newButtonEvents(SettingsData{ButtonDebounceMs: -1})
+	start := time.Now()
+
+	buttons.update(LeftButtonId, true, start)
+	buttons.update(LeftButtonId, false, start)
+
+	Assert(len(receivedPresses(buttons.events)), 2, "presses", t)
+}
+
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
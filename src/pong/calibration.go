@@ -0,0 +1,114 @@
+package pong
+
+import (
+	"log"
+	"math"
+	"strings"
+)
+
+// Converts colors to the values sent to a chipset: white balance, gamma correction and channel order
+type ColorCalibration struct {
+
+	// lookup for red, green and blue applying gamma correction and then the channel's gain
+	lookup [3][256]uint8
+
+	// channel sent first, second and third, 0 is red, 1 green and 2 blue
+	order [3]int
+}
+
+// Build the calibration from settings for a chipset whose channels go up to maxValue and are sent in defaultOrder
+func NewColorCalibration(settings SettingsData, defaultOrder string, maxValue float64) *ColorCalibration {
+
+	exponent := settings.GammaExponent
+	if exponent <= 0 {
+		exponent = defaultGammaExponent
+	}
+
+	order := settings.ColorOrder
+	if order == "" {
+		order = defaultOrder
+	}
+
+	calibration := &ColorCalibration{
+		order: parseColorOrder(order),
+	}
+
+	for channel, gain := range []float64{settings.RedGain, settings.GreenGain, settings.BlueGain} {
+		if gain <= 0 || 1 < gain {
+			gain = 1
+		}
+		calibration.lookup[channel] = buildGammaTable(exponent, gain, maxValue)
+	}
+
+	return calibration
+}
+
+// Precompute x = pow(i / 255, exponent) * gain * maxValue rounded to the nearest integer
+func buildGammaTable(exponent, gain, maxValue float64) (table [256]uint8) {
+	for index := range table {
+		table[index] = uint8(math.Pow(float64(index)/255.0, exponent)*gain*maxValue + 0.5)
+	}
+	return
+}
+
+// Convert a color order such as GRB to the channel sent in each position
+func parseColorOrder(order string) (channels [3]int) {
+
+	order = strings.ToUpper(order)
+	if len(order) != 3 {
+		log.Fatal("Color order must name each of R, G and B once, not ", order)
+	}
+
+	seen := [3]bool{}
+	for position, letter := range order {
+		channel := strings.IndexRune("RGB", letter)
+		if channel < 0 || seen[channel] {
+			log.Fatal("Color order must name each of R, G and B once, not ", order)
+		}
+		seen[channel] = true
+		channels[position] = channel
+	}
+
+	return
+}
+
+// Calibrated values of color in the order they are sent
+func (this *ColorCalibration) Channels(color RGBA) (first, second, third uint8) {
+
+	values := [3]uint8{color.R, color.G, color.B}
+
+	first = this.lookup[this.order[0]][values[this.order[0]]]
+	second = this.lookup[this.order[1]][values[this.order[1]]]
+	third = this.lookup[this.order[2]][values[this.order[2]]]
+	return
+}
+
+// A display that can take new calibration settings while running
+type CalibratedDisplay interface {
+	Display
+
+	// Rebuild anything that depends on the calibration settings
+	Calibrate(settings SettingsData)
+}
+
+// A display that passes frames on to other displays
+type DisplayWrapper interface {
+	Display
+
+	// The displays frames are passed on to
+	WrappedDisplays() []Display
+}
+
+// Apply calibration settings to display and to every display it wraps
+func Calibrate(display Display, settings SettingsData) {
+
+	if calibrated, ok := display.(CalibratedDisplay); ok {
+		calibrated.Calibrate(settings)
+	}
+
+	if wrapper, ok := display.(DisplayWrapper); ok {
+		for _, wrapped := range wrapper.WrappedDisplays() {
+			Calibrate(wrapped, settings)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package pong
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_ColorCalibration_DefaultMatchesLegacyTable(t *testing.T) {
+	calibration := NewColorCalibration(SettingsData{}, "GRB", 127)
+
+	for value := 0; value < 256; value++ {
+		green, red, blue := calibration.Channels(RGBA{uint8(value), uint8(value), uint8(value), 255})
+		Assert(int(red), int(legacyLpd8806Gamma[value]), "red", t)
+		Assert(int(green), int(legacyLpd8806Gamma[value]), "green", t)
+		Assert(int(blue), int(legacyLpd8806Gamma[value]), "blue", t)
+	}
+}
+
+func Test_ColorCalibration_Order(t *testing.T) {
+	calibration := NewColorCalibration(SettingsData{GammaExponent: 1, ColorOrder: "brg"}, "RGB", 255)
+
+	first, second, third := calibration.Channels(RGBA{10, 20, 30, 255})
+	Assert(int(first), 30, "blue first", t)
+	Assert(int(second), 10, "red second", t)
+	Assert(int(third), 20, "green third", t)
+}
+
+func Test_ColorCalibration_Gain(t *testing.T) {
+	calibration := NewColorCalibration(SettingsData{GammaExponent: 1, RedGain: 0.5, BlueGain: 0.8}, "RGB", 255)
+
+	red, green, blue := calibration.Channels(RGBA{255, 255, 255, 255})
+	Assert(int(red), 128, "red", t)
+	Assert(int(green), 255, "green keeps full gain", t)
+	Assert(int(blue), 204, "blue", t)
+}
+
+func Test_LedDisplay_Calibrate(t *testing.T) {
+	bus := &fakeSpiBus{}
+	display := newLedDisplayOnBus(bus, NewChipset(SettingsData{Chipset: WS2801}), len(chipsetTestColors))
+
+	Calibrate(NewMatrixDisplay(display, NewPixelMap(2, 1, RowMajor, 0)), SettingsData{Chipset: WS2801, GammaExponent: 1, ColorOrder: "BGR"})
+	display.Render(chipsetTestColors)
+
+	expected := []byte{0, 0, 255, 255, 128, 0}
+	if !bytes.Equal(bus.writes[0], expected) {
+		t.Fatalf("wrote % x, expected % x", bus.writes[0], expected)
+	}
+}
+
+// LPD8806 table the lookups used to be hard coded to, generated tables with default settings must match it
+var legacyLpd8806Gamma = [256]uint8{
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 2, 2, 2, 2,
+	2, 2, 2, 2, 2, 3, 3, 3, 3, 3, 3, 3, 3, 4, 4, 4,
+	4, 4, 4, 4, 5, 5, 5, 5, 5, 6, 6, 6, 6, 6, 7, 7,
+	7, 7, 7, 8, 8, 8, 8, 9, 9, 9, 9, 10, 10, 10, 10, 11,
+	11, 11, 12, 12, 12, 13, 13, 13, 13, 14, 14, 14, 15, 15, 16, 16,
+	16, 17, 17, 17, 18, 18, 18, 19, 19, 20, 20, 21, 21, 21, 22, 22,
+	23, 23, 24, 24, 24, 25, 25, 26, 26, 27, 27, 28, 28, 29, 29, 30,
+	30, 31, 32, 32, 33, 33, 34, 34, 35, 35, 36, 37, 37, 38, 38, 39,
+	40, 40, 41, 41, 42, 43, 43, 44, 45, 45, 46, 47, 47, 48, 49, 50,
+	50, 51, 52, 52, 53, 54, 55, 55, 56, 57, 58, 58, 59, 60, 61, 62,
+	62, 63, 64, 65, 66, 67, 67, 68, 69, 70, 71, 72, 73, 74, 74, 75,
+	76, 77, 78, 79, 80, 81, 82, 83, 84, 85, 86, 87, 88, 89, 90, 91,
+	92, 93, 94, 95, 96, 97, 98, 99, 100, 101, 102, 104, 105, 106, 107, 108,
+	109, 110, 111, 113, 114, 115, 116, 117, 118, 120, 121, 122, 123, 125, 126, 127,
+}
@@ -0,0 +1,193 @@
+package pong
+
+import (
+	"log"
+	"strings"
+)
+
+// Encodes colors into the bytes a particular LED driver chip expects on the SPI bus
+type Chipset interface {
+
+	// Number of bytes needed to send ledCount colors, including any framing
+	FrameSize(ledCount int) int
+
+	// Encode colors into frame, which is FrameSize(len(colors)) bytes long
+	Encode(colors []RGBA, frame []byte)
+}
+
+// Names of the supported chipsets, used in SettingsData.Chipset
+const (
+	LPD8806 = "LPD8806"
+	APA102  = "APA102"
+	WS2801  = "WS2801"
+	WS2812  = "WS2812"
+)
+
+// Gamma correction used when GammaExponent isn't set
+const defaultGammaExponent = 2.5
+
+// Construct the Chipset named in settings, defaults to LPD8806
+func NewChipset(settings SettingsData) Chipset {
+
+	switch strings.ToUpper(settings.Chipset) {
+	case "", LPD8806:
+		return &LPD8806Chipset{calibration: NewColorCalibration(settings, "GRB", 127)}
+	case APA102, "SK9822":
+		brightness := settings.Apa102Brightness
+		if brightness <= 0 || 31 < brightness {
+			brightness = 31
+		}
+		return &APA102Chipset{brightness: uint8(brightness), calibration: NewColorCalibration(settings, "BGR", 255)}
+	case WS2801:
+		return &WS2801Chipset{calibration: NewColorCalibration(settings, "RGB", 255)}
+	case WS2812, "WS2812B", "WS281X", "SK6812":
+		if settings.SpiBusSpeedHz < 2000000 || 3200000 < settings.SpiBusSpeedHz {
+			log.Print("WS2812 over SPI expects a bus speed near 2400000 Hz, configured ", settings.SpiBusSpeedHz)
+		}
+		return &WS2812Chipset{calibration: NewColorCalibration(settings, "GRB", 255)}
+	}
+
+	log.Fatal("Unknown chipset ", settings.Chipset)
+	return nil
+}
+
+// LPD8806, 7 bit GRB with the high bit set on every byte
+type LPD8806Chipset struct {
+	calibration *ColorCalibration
+}
+
+var _ Chipset = &LPD8806Chipset{}
+
+// 4 zero bytes on front and end of the color data
+func (this *LPD8806Chipset) FrameSize(ledCount int) int {
+	return 4 + ledCount*3 + 4
+}
+
+// Encode in the calibration's order, G, R, B by default, with the 0x80 flag
+func (this *LPD8806Chipset) Encode(colors []RGBA, frame []byte) {
+	for colorIndex, color := range colors {
+		byteIndex := colorIndex*3 + 4
+
+		first, second, third := this.calibration.Channels(color)
+		frame[byteIndex+0] = first | 0x80
+		frame[byteIndex+1] = second | 0x80
+		frame[byteIndex+2] = third | 0x80
+	}
+}
+
+// APA102 (DotStar), 32 bit start frame, 0xE0 | brightness followed by B, G, R per led, then an end frame
+type APA102Chipset struct {
+
+	// global 5 bit brightness sent with every led
+	brightness uint8
+
+	calibration *ColorCalibration
+}
+
+var _ Chipset = &APA102Chipset{}
+
+// Number of end frame bytes, needs half a clock per led to push data through the chain, minimum of 4 bytes
+func apa102EndFrameSize(ledCount int) int {
+	size := (ledCount + 15) / 16
+	if size < 4 {
+		size = 4
+	}
+	return size
+}
+
+// Start frame, 4 bytes per led and end frame
+func (this *APA102Chipset) FrameSize(ledCount int) int {
+	return 4 + ledCount*4 + apa102EndFrameSize(ledCount)
+}
+
+// Encode as brightness followed by the colors in the calibration's order, B, G, R by default
+func (this *APA102Chipset) Encode(colors []RGBA, frame []byte) {
+
+	frame[0], frame[1], frame[2], frame[3] = 0, 0, 0, 0
+
+	for colorIndex, color := range colors {
+		byteIndex := colorIndex*4 + 4
+
+		frame[byteIndex+0] = 0xE0 | this.brightness
+		frame[byteIndex+1], frame[byteIndex+2], frame[byteIndex+3] = this.calibration.Channels(color)
+	}
+
+	for byteIndex := 4 + len(colors)*4; byteIndex < len(frame); byteIndex++ {
+		frame[byteIndex] = 0xFF
+	}
+}
+
+// WS2801, plain 8 bit RGB, the chip latches when the clock idles so there is no framing
+type WS2801Chipset struct {
+	calibration *ColorCalibration
+}
+
+var _ Chipset = &WS2801Chipset{}
+
+// 3 bytes per led
+func (this *WS2801Chipset) FrameSize(ledCount int) int {
+	return ledCount * 3
+}
+
+// Encode in the calibration's order, R, G, B by default
+func (this *WS2801Chipset) Encode(colors []RGBA, frame []byte) {
+	for colorIndex, color := range colors {
+		byteIndex := colorIndex * 3
+
+		frame[byteIndex+0], frame[byteIndex+1], frame[byteIndex+2] = this.calibration.Channels(color)
+	}
+}
+
+// WS2812 (NeoPixel) driven from the SPI MOSI pin at 2.4MHz, every data bit is sent as 3 SPI bits
+//
+//	0 is sent as 100 (~0.4us high, ~0.8us low)
+//	1 is sent as 110 (~0.8us high, ~0.4us low)
+type WS2812Chipset struct {
+	calibration *ColorCalibration
+}
+
+var _ Chipset = &WS2812Chipset{}
+
+// Zero bytes sent after the data to latch it, 300us at 2.4MHz covers the longer reset of the WS2812B
+const ws2812ResetBytes = 90
+
+// 9 bytes per led, one leading zero byte to make sure the line starts low, then the reset time
+func (this *WS2812Chipset) FrameSize(ledCount int) int {
+	return 1 + ledCount*9 + ws2812ResetBytes
+}
+
+// Encode in the calibration's order, G, R, B by default, with each bit expanded to 3 SPI bits
+func (this *WS2812Chipset) Encode(colors []RGBA, frame []byte) {
+
+	frame[0] = 0
+
+	for colorIndex, color := range colors {
+		byteIndex := colorIndex*9 + 1
+
+		first, second, third := this.calibration.Channels(color)
+		ws2812EncodeByte(first, frame[byteIndex+0:byteIndex+3])
+		ws2812EncodeByte(second, frame[byteIndex+3:byteIndex+6])
+		ws2812EncodeByte(third, frame[byteIndex+6:byteIndex+9])
+	}
+
+	for byteIndex := 1 + len(colors)*9; byteIndex < len(frame); byteIndex++ {
+		frame[byteIndex] = 0
+	}
+}
+
+// Expand the 8 bits of value, most significant first, into 24 SPI bits
+func ws2812EncodeByte(value uint8, out []byte) {
+
+	var bits uint32
+	for bit := 7; bit >= 0; bit-- {
+		if value&(1<<uint(bit)) != 0 {
+			bits = bits<<3 | 6 // 110
+		} else {
+			bits = bits<<3 | 4 // 100
+		}
+	}
+
+	out[0] = byte(bits >> 16)
+	out[1] = byte(bits >> 8)
+	out[2] = byte(bits)
+}
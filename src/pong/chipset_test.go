@@ -0,0 +1,81 @@
+package pong
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Records everything written to it instead of talking to the SPI device
+type fakeSpiBus struct {
+	writes [][]byte
+}
+
+func (bus *fakeSpiBus) Write(data []byte) (n int, err error) {
+	bus.writes = append(bus.writes, append([]byte(nil), data...))
+	return len(data), nil
+}
+
+// red, then half green with full blue
+var chipsetTestColors = []RGBA{{255, 0, 0, 255}, {0, 128, 255, 255}}
+
+// Render chipsetTestColors with the named chipset and check the exact bytes on the bus
+func assertChipsetBytes(chipsetName string, expected []byte, t *testing.T) {
+	bus := &fakeSpiBus{}
+	display := newLedDisplayOnBus(bus, NewChipset(SettingsData{Chipset: chipsetName, SpiBusSpeedHz: 2400000}), len(chipsetTestColors))
+
+	display.Render(chipsetTestColors)
+
+	Assert(len(bus.writes), 1, chipsetName+" writes", t)
+	if !bytes.Equal(bus.writes[0], expected) {
+		t.Fatalf("%s wrote\n% x\nexpected\n% x", chipsetName, bus.writes[0], expected)
+	}
+}
+
+func Test_Chipset_LPD8806(t *testing.T) {
+	assertChipsetBytes(LPD8806, []byte{
+		0, 0, 0, 0,
+		0x80, 0xFF, 0x80, // G R B
+		0x97, 0x80, 0xFF,
+		0, 0, 0, 0,
+	}, t)
+}
+
+func Test_Chipset_APA102(t *testing.T) {
+	assertChipsetBytes(APA102, []byte{
+		0, 0, 0, 0,
+		0xFF, 0, 0, 255, // brightness B G R
+		0xFF, 255, 46, 0,
+		0xFF, 0xFF, 0xFF, 0xFF,
+	}, t)
+}
+
+func Test_Chipset_WS2801(t *testing.T) {
+	assertChipsetBytes(WS2801, []byte{
+		255, 0, 0,
+		0, 46, 255,
+	}, t)
+}
+
+func Test_Chipset_WS2812(t *testing.T) {
+	zero := []byte{0x92, 0x49, 0x24}
+	full := []byte{0xDB, 0x6D, 0xB6}
+	fortySix := []byte{0x93, 0x4D, 0xB4}
+
+	expected := []byte{0}
+	expected = append(expected, zero...) // G R B
+	expected = append(expected, full...)
+	expected = append(expected, zero...)
+	expected = append(expected, fortySix...)
+	expected = append(expected, zero...)
+	expected = append(expected, full...)
+	expected = append(expected, make([]byte, ws2812ResetBytes)...)
+
+	assertChipsetBytes(WS2812, expected, t)
+}
+
+// The APA102 end frame has to grow with long strips
+func Test_Chipset_APA102EndFrame(t *testing.T) {
+	Assert(apa102EndFrameSize(10), 4, "Short strip end frame", t)
+	Assert(apa102EndFrameSize(64), 4, "64 led end frame", t)
+	Assert(apa102EndFrameSize(100), 7, "100 led end frame", t)
+}
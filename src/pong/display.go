@@ -5,8 +5,10 @@ import (
 	"image"
 	"image/color"
 	"image/png"
+	"io"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -17,16 +19,34 @@ type Display interface {
 
 // Web display
 type WebDisplay struct {
-	previousRender []RGBA
+
+	// latest rendered frame, shared with the http handlers
+	frames *FrameStore
+
+	// number of rows in each frame, more than 1 for a 2d matrix field
+	rows int
+
+	// the page shows the buttons of the inner players
+	doubles bool
+
+	// on-screen buttons of the page
+	buttons *buttonEvents
 }
 
-var testWebDisplay Display = &WebDisplay{}
+var testWebDisplay InputDisplay = &WebDisplay{}
+
+// how long a stream handler waits for a new frame before checking if the client left
+const streamFrameTimeout = time.Second
 
 // Create a new WebDisplay
 func NewWebDisplay(settings SettingsData) *WebDisplay {
 	display := &WebDisplay{
-		previousRender: make([]RGBA, settings.LedCount),
+		frames:  NewFrameStore(),
+		rows:    settings.FieldHeight(),
+		doubles: settings.Doubles,
+		buttons: newButtonEvents(SettingsData{ButtonDebounceMs: -1}),
 	}
+	display.frames.Store(make([]RGBA, settings.FieldWidth()*settings.FieldHeight()))
 
 	go display.LaunchWebServer()
 	return display
@@ -34,35 +54,231 @@ func NewWebDisplay(settings SettingsData) *WebDisplay {
 
 // Render the field to an internal structure, that can be read out by the webserver
 func (this *WebDisplay) Render(data []RGBA) {
+	this.frames.Store(data)
+}
+
+// Frames rendered to this display, for other consumers that want to share them
+func (this *WebDisplay) Frames() *FrameStore {
+	return this.frames
+}
 
-	this.previousRender = data
+// Buttons pushed on the page
+func (this *WebDisplay) Input() InputSource {
+	return this.buttons
+}
+
+// Encode colors as packed RGB triplets, the format sent to the browser
+func encodeFrame(data []RGBA) []byte {
+	frame := make([]byte, len(data)*3)
+	for index, color := range data {
+		frame[index*3+0] = color.R
+		frame[index*3+1] = color.G
+		frame[index*3+2] = color.B
+	}
+	return frame
 }
 
 // Launches the webserver
 func (this *WebDisplay) LaunchWebServer() {
 
-	http.HandleFunc("/", htmlPageHandler)
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { htmlPageHandler(w, r, this.rows, this.doubles) })
 	http.HandleFunc("/image/", func(w http.ResponseWriter, r *http.Request) { this.imageHandler(w, r) })
+	http.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) { this.streamHandler(w, r) })
 
 	log.Print("Server listening on 8080")
 	http.ListenAndServe(":8080", nil)
 }
 
-// Serve static html page
-func htmlPageHandler(w http.ResponseWriter, r *http.Request) {
+// Serve static html page, showing frames of the given number of rows and the inner players' buttons in doubles
+func htmlPageHandler(w http.ResponseWriter, r *http.Request, rows int, doubles bool) {
+
+	innerDisplay := "none"
+	if doubles {
+		innerDisplay = "inline-block"
+	}
+
 	fmt.Fprintf(w, `
 <html>
-	<head><script type="text/javascript"><!--
-		function reloadpic()
-        {
-			document.images["gameBoard"].src = "image/test.png";
-			setTimeout(reloadpic, 100);
-        }
-        setTimeout(reloadpic, 100)
-	--></script></head>
-	<body><img id="gameBoard" src="image/test.png" height="24" width="1024"/></body>
-</html>`)
+	<head>
+		<style>
+			body { background: #111; }
+			#gameBoard { width: 1024px; height: 24px; image-rendering: pixelated; image-rendering: crisp-edges; }
+			.button { width: 160px; height: 160px; margin: 24px; border-radius: 50%%; border: none; font-size: 20px; touch-action: none; user-select: none; }
+			#left { background: #22f; }
+			#right { background: #2d2; float: right; }
+			#leftinner { background: #a0f; }
+			#rightinner { background: #cf0; float: right; }
+			.inner { display: %s; }
+			.button.down { filter: brightness(60%%); }
+		</style>
+	</head>
+	<body>
+		<canvas id="gameBoard" width="1" height="1"></canvas>
+		<div style="width: 1024px">
+			<button id="left" class="button">A</button>
+			<button id="leftinner" class="button inner">S</button>
+			<button id="right" class="button">L</button>
+			<button id="rightinner" class="button inner">K</button>
+		</div>
+		<script type="text/javascript"><!--
+		var canvas = document.getElementById("gameBoard");
+		var context = canvas.getContext("2d");
+		var pending = null;
+		var rows = %d;
+
+		// only draw the newest frame once per animation frame
+		function draw() {
+			if (pending != null) {
+				var rgb = pending;
+				pending = null;
+
+				var ledCount = rgb.length / 3;
+				var columns = ledCount / rows;
+				if (canvas.width != columns || canvas.height != rows) {
+					canvas.width = columns;
+					canvas.height = rows;
+
+					// a matrix is drawn with square leds
+					if (rows > 1) {
+						canvas.style.width = (columns * 24) + "px";
+						canvas.style.height = (rows * 24) + "px";
+					}
+				}
+
+				var image = context.createImageData(columns, rows);
+				for (var i = 0; i < ledCount; i++) {
+					image.data[i*4+0] = rgb[i*3+0];
+					image.data[i*4+1] = rgb[i*3+1];
+					image.data[i*4+2] = rgb[i*3+2];
+					image.data[i*4+3] = 255;
+				}
+				context.putImageData(image, 0, 0);
+			}
+			window.requestAnimationFrame(draw);
+		}
+
+		var socket = null;
+		function connect() {
+			socket = new WebSocket("ws://" + window.location.host + "/stream");
+			socket.binaryType = "arraybuffer";
+			socket.onmessage = function(event) { pending = new Uint8Array(event.data); };
+			socket.onclose = function() { setTimeout(connect, 1000); };
+		}
+
+		// buttons are sent as "left down", "rightinner up" and so on
+		function press(side, down) {
+			var button = document.getElementById(side);
+			if (button.classList.contains("down") == down) {
+				return;
+			}
+			button.classList.toggle("down", down);
+			if (socket != null && socket.readyState == WebSocket.OPEN) {
+				socket.send(side + (down ? " down" : " up"));
+			}
+		}
+
+		["left", "right", "leftinner", "rightinner"].forEach(function(side) {
+			var button = document.getElementById(side);
+			button.onpointerdown = function(event) { button.setPointerCapture(event.pointerId); press(side, true); };
+			button.onpointerup = function() { press(side, false); };
+			button.onpointercancel = function() { press(side, false); };
+		});
+
+		var keys = { "a": "left", "l": "right", "s": "leftinner", "k": "rightinner" };
+		document.onkeydown = function(event) { if (keys[event.key]) press(keys[event.key], true); };
+		document.onkeyup = function(event) { if (keys[event.key]) press(keys[event.key], false); };
+
+		connect();
+		window.requestAnimationFrame(draw);
+		--></script>
+	</body>
+</html>`, innerDisplay, rows)
+
+}
+
+// Stream every rendered frame to the browser over a websocket
+func (this *WebDisplay) streamHandler(w http.ResponseWriter, r *http.Request) {
+
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		log.Print("Websocket upgrade failed ", err)
+		return
+	}
+	defer conn.Close()
+
+	// the browser only sends its buttons, reading also detects the close
+	closed := make(chan bool)
+	go func() {
+		this.readButtons(conn)
+		close(closed)
+	}()
+
+	log.Print("Stream client connected ", r.RemoteAddr)
+
+	// always send the newest frame, a slow client simply skips the ones in between
+	var sequence uint64
+	for {
+		select {
+		case <-closed:
+			log.Print("Stream client disconnected ", r.RemoteAddr)
+			return
+		default:
+		}
+
+		frame := this.frames.WaitNewer(sequence, streamFrameTimeout)
+		if frame == nil {
+			continue
+		}
+		sequence = frame.Sequence
+
+		if err := conn.WriteMessage(wsBinary, encodeFrame(frame.Colors)); err != nil {
+			log.Print("Stream client ", r.RemoteAddr, " dropped ", err)
+			return
+		}
+	}
+}
+
+// Buttons by their id on the page
+var webButtons = map[string]ButtonId{
+	"left":       LeftButtonId,
+	"right":      RightButtonId,
+	"leftinner":  LeftInnerButtonId,
+	"rightinner": RightInnerButtonId,
+}
+
+// Apply button messages from a client until it leaves, its buttons are released when it does
+func (this *WebDisplay) readButtons(conn *webSocketConn) {
+
+	var held [ButtonCount]bool
+	defer func() {
+		for button, down := range held {
+			if down {
+				this.buttons.update(ButtonId(button), false, time.Now())
+			}
+		}
+	}()
+
+	for {
+		opcode, payload, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if opcode != wsText {
+			continue
+		}
 
+		var name, state string
+		fmt.Sscan(string(payload), &name, &state)
+		button, ok := webButtons[name]
+		if !ok || (state != "down" && state != "up") {
+			log.Print("Unknown message from stream client ", string(payload))
+			continue
+		}
+		down := state == "down"
+
+		held[button] = down
+		this.buttons.update(button, down, time.Now())
+	}
 }
 
 // Return newly generating image
@@ -73,16 +289,16 @@ func (this *WebDisplay) imageHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "image/png")
 	w.Header().Set("Cache-control", "max-age=0, must-revalidate, no-store")
 
-	data := this.previousRender
+	data := this.frames.Latest().Colors
 
 	spacing := 1
-	width, height := len(data), 1
+	width, height := len(data)/this.rows, this.rows
 	image := image.NewRGBA(image.Rect(0, 0, width*spacing, height))
 
 	for dataIndex := 0; dataIndex < width; dataIndex++ {
 
 		for y := 0; y < height; y++ {
-			displayedColor := color.RGBA(data[dataIndex])
+			displayedColor := color.RGBA(data[y*width+dataIndex])
 			displayedColor.A = 255
 			image.Set(dataIndex*spacing, y, displayedColor)
 		}
@@ -94,20 +310,30 @@ func (this *WebDisplay) imageHandler(w http.ResponseWriter, r *http.Request) {
 
 // RGB LED Display
 type LedDisplay struct {
-	bus *SpiBus
+	bus io.Writer
+
+	// converts colors to the bytes expected by the leds, replaced when the display is calibrated
+	chipset     Chipset
+	chipsetLock sync.Mutex
 
 	expectedColors int
 	byteData       []byte
 }
 
-var testLedDisplay Display = &LedDisplay{}
+var testLedDisplay CalibratedDisplay = &LedDisplay{}
 
 // Construct an LedDisplay
 func NewLedDisplay(settings SettingsData) *LedDisplay {
+	return newLedDisplayOnBus(NewSpiBus(settings.SpiFilePath, settings.SpiBusSpeedHz), NewChipset(settings), settings.LedCount)
+}
+
+// Construct an LedDisplay that writes to any bus
+func newLedDisplayOnBus(bus io.Writer, chipset Chipset, ledCount int) *LedDisplay {
 	return &LedDisplay{
-		bus:            NewSpiBus(settings.SpiFilePath, settings.SpiBusSpeedHz),
-		expectedColors: settings.LedCount,
-		byteData:       make([]byte, 4+settings.LedCount*3+4), // +8 is for the null bytes on front and end of data
+		bus:            bus,
+		chipset:        chipset,
+		expectedColors: ledCount,
+		byteData:       make([]byte, chipset.FrameSize(ledCount)),
 	}
 }
 
@@ -117,35 +343,22 @@ func (this *LedDisplay) Render(colorData []RGBA) {
 		log.Fatal("colorData was not the expected length of ", this.expectedColors, " saw ", len(colorData))
 	}
 
-	for colorIndex := 0; colorIndex < len(colorData); colorIndex++ {
-		color := colorData[colorIndex]
-		byteIndex := colorIndex*3 + 4
-
-		this.byteData[byteIndex+0] = gammaCorrectionLookup[color.G] | 0x80
-		this.byteData[byteIndex+1] = gammaCorrectionLookup[color.R] | 0x80
-		this.byteData[byteIndex+2] = gammaCorrectionLookup[color.B] | 0x80
-	}
+	// Calibrate swaps byteData along with the chipset, so the frame is written before letting go
+	this.chipsetLock.Lock()
+	defer this.chipsetLock.Unlock()
 
+	this.chipset.Encode(colorData, this.byteData)
 	this.bus.Write(this.byteData)
 }
 
-// based on a pull request found at http://forums.adafruit.com/viewtopic.php?f=47&t=26591
-// is basically precomputing x = pow(i / 255, 3.0) * 127
-var gammaCorrectionLookup [256]uint8 = [256]uint8{
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1,
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 2, 2, 2, 2,
-	2, 2, 2, 2, 2, 3, 3, 3, 3, 3, 3, 3, 3, 4, 4, 4,
-	4, 4, 4, 4, 5, 5, 5, 5, 5, 6, 6, 6, 6, 6, 7, 7,
-	7, 7, 7, 8, 8, 8, 8, 9, 9, 9, 9, 10, 10, 10, 10, 11,
-	11, 11, 12, 12, 12, 13, 13, 13, 13, 14, 14, 14, 15, 15, 16, 16,
-	16, 17, 17, 17, 18, 18, 18, 19, 19, 20, 20, 21, 21, 21, 22, 22,
-	23, 23, 24, 24, 24, 25, 25, 26, 26, 27, 27, 28, 28, 29, 29, 30,
-	30, 31, 32, 32, 33, 33, 34, 34, 35, 35, 36, 37, 37, 38, 38, 39,
-	40, 40, 41, 41, 42, 43, 43, 44, 45, 45, 46, 47, 47, 48, 49, 50,
-	50, 51, 52, 52, 53, 54, 55, 55, 56, 57, 58, 58, 59, 60, 61, 62,
-	62, 63, 64, 65, 66, 67, 67, 68, 69, 70, 71, 72, 73, 74, 74, 75,
-	76, 77, 78, 79, 80, 81, 82, 83, 84, 85, 86, 87, 88, 89, 90, 91,
-	92, 93, 94, 95, 96, 97, 98, 99, 100, 101, 102, 104, 105, 106, 107, 108,
-	109, 110, 111, 113, 114, 115, 116, 117, 118, 120, 121, 122, 123, 125, 126, 127,
+// Rebuild the chipset with new calibration settings
+func (this *LedDisplay) Calibrate(settings SettingsData) {
+
+	chipset := NewChipset(settings)
+
+	this.chipsetLock.Lock()
+	defer this.chipsetLock.Unlock()
+
+	this.chipset = chipset
+	this.byteData = make([]byte, chipset.FrameSize(this.expectedColors))
 }
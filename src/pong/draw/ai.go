@@ -0,0 +1,172 @@
+package draw
+
+import (
+	"math"
+	. "pong"
+)
+
+// how long the paddle is held at most after the ball should have arrived
+const aiMaxLateHold = 0.2
+
+// The ball as the computer player saw it at some time
+type ballObservation struct {
+	time, position, velocity float64
+}
+
+// Computer player driving the paddle of a Player from the Ball
+type AIPlayer struct {
+	player *Player
+	ball   *Ball
+
+	difficulty AIDifficulty
+
+	// random numbers of the field, so the computer plays the same way in a replayed game
+	random *Random
+
+	// time since the game started and what the ball did during the last ReactionDelay
+	clock   float64
+	history []ballObservation
+
+	// what was seen on the previous update
+	previous ballObservation
+
+	// plan for the current return, the paddle is pushed once the ball is pushAhead seconds away
+	returning bool
+	pushAhead float64
+
+	// plan while the ball is heading to the opponent
+	hide          bool
+	hideRemaining float64
+}
+
+var _ Snapshotter = &AIPlayer{}
+
+// Let the computer play player on field at the named difficulty, an error if there's no such difficulty
+func NewAIPlayer(field *GameField, player *Player, ball *Ball, difficulty string) (*AIPlayer, error) {
+
+	settings, err := AIDifficultyNamed(difficulty)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AIPlayer{
+		player:     player,
+		ball:       ball,
+		difficulty: settings,
+		random:     field.Random(),
+	}, nil
+}
+
+// Copy of what the computer player saw and planned
+func (this *AIPlayer) Snapshot() interface{} {
+	snapshot := *this
+	snapshot.history = append([]ballObservation(nil), this.history...)
+	return snapshot
+}
+
+// Go back to a state from Snapshot
+func (this *AIPlayer) Restore(snapshot interface{}) {
+	*this = snapshot.(AIPlayer)
+	this.history = append([]ballObservation(nil), this.history...)
+}
+
+// Watch the ball that reaches the player soonest, what was seen of another ball before is forgotten
+func (this *AIPlayer) FollowNearest(balls []*Ball) {
+
+	nearest, soonest := balls[0], math.Inf(1)
+	for _, ball := range balls {
+		distance, closing := this.relative(ballObservation{position: ball.Position(), velocity: ball.Velocity()})
+		if closing > 0 && distance/closing < soonest {
+			nearest, soonest = ball, distance/closing
+		}
+	}
+
+	if nearest != this.ball {
+		this.ball = nearest
+		this.history = nil
+	}
+}
+
+// Position of the ball relative to the player, positive while it's in front of the paddle, and the speed it closes in
+func (this *AIPlayer) relative(observation ballObservation) (distance, closing float64) {
+	if this.player.IsLeft() {
+		return observation.position - this.player.paddleRight, -observation.velocity
+	}
+	return this.player.paddleLeft - observation.position, observation.velocity
+}
+
+// What the ball did ReactionDelay ago
+func (this *AIPlayer) perceive() ballObservation {
+
+	this.history = append(this.history, ballObservation{this.clock, this.ball.Position(), this.ball.Velocity()})
+
+	seen := 0
+	for index, observation := range this.history {
+		if observation.time <= this.clock-this.difficulty.ReactionDelay {
+			seen = index
+		}
+	}
+
+	// forget what happened before the perceived moment
+	this.history = this.history[seen:]
+
+	return this.history[0]
+}
+
+// Decide if the paddle is held, call once per update of the game before the ball checks for hits
+func (this *AIPlayer) Update(dt float64) {
+
+	this.clock += dt
+	seen := this.perceive()
+
+	distance, closing := this.relative(seen)
+	_, previousClosing := this.relative(this.previous)
+	previousDistance, _ := this.relative(this.previous)
+
+	// the ball either turned towards the player, or was put back into play after a miss
+	if closing > 0 && (previousClosing <= 0 || distance > previousDistance+1) {
+		this.planReturn()
+	}
+	if closing <= 0 && previousClosing > 0 {
+		this.returning = false
+		this.hide = this.random.Float64() < this.difficulty.HideChance
+		this.hideRemaining = this.difficulty.HideTime
+	}
+	this.previous = seen
+
+	push := false
+	if this.returning && closing > 0 {
+
+		// the ball moved on since it was seen
+		arrival := distance/closing - this.difficulty.ReactionDelay
+
+		push = arrival <= this.pushAhead
+		if arrival < -aiMaxLateHold {
+			this.returning = false
+			push = false
+		}
+	} else if this.hide && closing < 0 && this.hideRemaining > 0 {
+
+		// hide the ball during the last part of its way to the opponent
+		fieldLength := this.ball.MaxPosition()
+		if distance > fieldLength*0.75 {
+			push = true
+			this.hideRemaining -= dt
+		}
+	}
+
+	this.player.UpdatePaddleActive(push)
+}
+
+// Pick how early the paddle is pushed for the coming return
+func (this *AIPlayer) planReturn() {
+
+	this.returning = true
+	this.pushAhead = this.difficulty.Lead + this.random.NormFloat64()*this.difficulty.TimingJitter
+
+	if this.random.Float64() < this.difficulty.WasteChance {
+		this.pushAhead += 0.3 + this.random.Float64()*0.5
+	}
+
+	this.pushAhead = math.Max(this.pushAhead, -aiMaxLateHold)
+}
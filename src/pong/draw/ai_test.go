@@ -0,0 +1,168 @@
+package draw
+
+import (
+	"math"
+	. "pong"
+	"testing"
+)
+
+func init() {
+	MuteSounds(true)
+}
+
+// Helper assert method
+func Assert(actual, expected int, message string, t *testing.T) {
+	if actual != expected {
+		t.Fatal(message, actual, "vs expected", expected)
+	}
+}
+
+// Helper assert method for times and positions
+func assertNear(actual, expected float64, message string, t *testing.T) {
+	if math.Abs(actual-expected) > 1e-9 {
+		t.Fatal(message, actual, "vs expected", expected)
+	}
+}
+
+// Field 60 leds wide whose random numbers start from seed
+func newTestField(seed uint64) *GameField {
+	field := NewGameField(60)
+	field.SetRandom(NewRandom(seed))
+	return field
+}
+
+// A computer on the right side playing a ball at difficulty
+func newTestAI(difficulty AIDifficulty, ball *Ball) *AIPlayer {
+	field := newTestField(1)
+	computer, err := NewAIPlayer(field, NewPlayer(false, 10, field), ball, "easy")
+	if err != nil {
+		panic(err)
+	}
+	computer.difficulty = difficulty
+	return computer
+}
+
+func Test_AIPlayer_UnknownDifficulty(t *testing.T) {
+	field := newTestField(1)
+	if _, err := NewAIPlayer(field, NewPlayer(false, 10, field), &Ball{}, "impossible"); err == nil {
+		t.Fatal("Unknown difficulty accepted")
+	}
+	if _, err := NewAIPlayer(field, NewPlayer(false, 10, field), &Ball{}, "Hard"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// The computer acts on where the ball was ReactionDelay ago and forgets what happened before that
+func Test_AIPlayer_ReactionDelay(t *testing.T) {
+	ball := &Ball{maxPosition: 59}
+	computer := newTestAI(AIDifficulty{ReactionDelay: 0.25}, ball)
+
+	for step, expected := range []float64{1, 1, 1, 2, 3, 4} {
+		computer.clock = float64(step+1) * 0.125
+		ball.position = float64(step + 1)
+		assertNear(computer.perceive().position, expected, "Position seen", t)
+	}
+	Assert(len(computer.history), 3, "Observations kept", t)
+}
+
+// Pushes are planned around Lead ahead of the ball, spread by TimingJitter, with a WasteChance of pushing much too early
+func Test_AIPlayer_PlanReturn(t *testing.T) {
+
+	var previousSpread float64
+	for _, name := range []string{"easy", "medium", "hard"} {
+		difficulty := AIDifficulties[name]
+		computer := newTestAI(difficulty, &Ball{maxPosition: 59})
+
+		const plans = 4000
+		var wasted, timed int
+		var sum, sumSquares float64
+		for plan := 0; plan < plans; plan++ {
+			computer.planReturn()
+			if !computer.returning {
+				t.Fatal(name, "isn't returning")
+			}
+			if computer.pushAhead > 0.25 {
+				wasted++
+				continue
+			}
+			timed++
+			sum += computer.pushAhead
+			sumSquares += computer.pushAhead * computer.pushAhead
+		}
+
+		mean := sum / float64(timed)
+		spread := math.Sqrt(sumSquares/float64(timed) - mean*mean)
+		if math.Abs(mean-difficulty.Lead) > 0.01 {
+			t.Fatal(name, "pushes", mean, "ahead instead of", difficulty.Lead)
+		}
+		if math.Abs(spread-difficulty.TimingJitter) > difficulty.TimingJitter*0.2 {
+			t.Fatal(name, "spread", spread, "instead of", difficulty.TimingJitter)
+		}
+		if math.Abs(float64(wasted)/plans-difficulty.WasteChance) > 0.03 {
+			t.Fatal(name, "wasted", wasted, "of", plans)
+		}
+		if previousSpread > 0 && spread >= previousSpread {
+			t.Fatal(name, "isn't more precise than the easier difficulty")
+		}
+		previousSpread = spread
+	}
+}
+
+// The computer on the right follows the ball that reaches it first, and forgets what it saw of the ball before
+func Test_AIPlayer_FollowNearest(t *testing.T) {
+	away := &Ball{position: 50, velocity: -30, maxPosition: 59}
+	slow := &Ball{position: 40, velocity: 10, maxPosition: 59}
+	fast := &Ball{position: 10, velocity: 30, maxPosition: 59}
+	balls := []*Ball{away, slow, fast}
+
+	computer := newTestAI(AIDifficulties["medium"], away)
+	computer.history = []ballObservation{{position: 50}}
+
+	computer.FollowNearest(balls)
+	if computer.ball != fast {
+		t.Fatal("Not following the ball that arrives first")
+	}
+	Assert(len(computer.history), 0, "History of another ball", t)
+
+	fast.velocity = -30
+	computer.FollowNearest(balls)
+	if computer.ball != slow {
+		t.Fatal("Not following the only ball heading here")
+	}
+
+	slow.velocity = -10
+	computer.FollowNearest(balls)
+	if computer.ball != away {
+		t.Fatal("Not following the first ball while all head away")
+	}
+}
+
+// After a return the computer may hold the paddle for HideTime while the ball is in the last quarter before the opponent
+func Test_AIPlayer_Hide(t *testing.T) {
+	ball := &Ball{position: 40, velocity: 30, maxPosition: 59}
+	computer := newTestAI(AIDifficulty{HideChance: 1, HideTime: 0.25}, ball)
+
+	// the ball comes in and goes back, then passes three quarters of the field and the paddle is held for two updates
+	steps := []struct {
+		position, velocity float64
+		held               bool
+	}{{40, 30, false}, {40, -30, false}, {20, -30, false}, {10, -30, true}, {8, -30, true}, {6, -30, false}}
+
+	for step, check := range steps {
+		ball.position, ball.velocity = check.position, check.velocity
+		computer.Update(0.125)
+		if computer.player.paddleActive != check.held {
+			t.Fatal("Paddle held", computer.player.paddleActive, "at step", step)
+		}
+	}
+
+	// never at a difficulty that doesn't hide
+	computer = newTestAI(AIDifficulty{}, ball)
+	for step, check := range steps {
+		ball.position, ball.velocity = check.position, check.velocity
+		computer.Update(0.125)
+		if computer.player.paddleActive {
+			t.Fatal("Paddle held without hiding at step", step)
+		}
+	}
+}
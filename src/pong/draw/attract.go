@@ -0,0 +1,128 @@
+package draw
+
+import (
+	"math"
+	. "pong"
+)
+
+// Shows the field returned by source below the drawables above it, like a match played by another SceneRunner
+type FieldLayer struct {
+	source func() *GameField
+	zindex ZIndex
+}
+
+var _ Drawable2D = &FieldLayer{}
+var _ Interpolated = &FieldLayer{}
+
+// Construct a FieldLayer, source is called whenever it's drawn so the field can change
+func NewFieldLayer(source func() *GameField, zindex ZIndex) *FieldLayer {
+	return &FieldLayer{source: source, zindex: zindex}
+}
+
+// Returns the color at position blended on top of baseColor
+func (this *FieldLayer) ColorAt(position float64, baseColor RGBA) RGBA {
+	return this.source().ColorAt(position).BlendWith(baseColor)
+}
+
+// Returns the color at x, y blended on top of baseColor
+func (this *FieldLayer) ColorAtXY(x, y float64, baseColor RGBA) RGBA {
+	return this.source().ColorAtXY(x, y).BlendWith(baseColor)
+}
+
+// Draw the source field alpha of the way between its last two updates
+func (this *FieldLayer) Interpolate(alpha float64) {
+	this.source().Interpolate(alpha)
+}
+
+// ZIndex
+func (this *FieldLayer) ZIndex() ZIndex {
+	return this.zindex
+}
+
+// Animate, the source field is moved on by whoever owns it
+func (this *FieldLayer) Animate(dt float64) bool {
+	return true
+}
+
+// Blinks a prompt over whatever is below it. The text scrolls by over and over on fields tall enough for the font,
+// on the others the leds at both ends blink where the paddles are
+type Prompt struct {
+
+	// scrolls the text, its time is kept within its duration
+	ticker Ticker
+
+	color  RGBA
+	width  float64
+	zindex ZIndex
+
+	// total time counted so far
+	time float64
+}
+
+var _ Drawable2D = &Prompt{}
+var _ Snapshotter = &Prompt{}
+
+// seconds of one blink of the prompt and the share of it the prompt is shown
+const promptBlinkTime, promptShown = 1.0, 0.7
+
+// leds blinking at each end of a field too low for the text
+const promptEndLeds = 3
+
+// Construct a Prompt showing text in color
+func NewPrompt(field *GameField, text string, color RGBA, zindex ZIndex) *Prompt {
+	return &Prompt{
+		ticker: *NewTicker(field, []TickerEntry{{Text: text, Color: color}}, zindex),
+		color:  color,
+		width:  float64(field.Width()),
+		zindex: zindex,
+	}
+}
+
+// True while the prompt is shown between blinks
+func (this *Prompt) shown() bool {
+	return math.Mod(this.time, promptBlinkTime) < promptBlinkTime*promptShown
+}
+
+// Returns the color at position blended on top of baseColor
+func (this *Prompt) ColorAt(position float64, baseColor RGBA) RGBA {
+	if this.shown() && (position < promptEndLeds || position >= this.width-promptEndLeds) {
+		return this.color.BlendWith(baseColor)
+	}
+	return baseColor
+}
+
+// Returns the color at x, y blended on top of baseColor
+func (this *Prompt) ColorAtXY(x, y float64, baseColor RGBA) RGBA {
+
+	if this.ticker.rows == nil {
+		return this.ColorAt(x, baseColor)
+	}
+
+	row := int(y) - this.ticker.top
+	if column, ok := this.ticker.column(x); ok && this.shown() && 0 <= row && row < tickerFontHeight && this.ticker.rows[row][column] {
+		return this.ticker.columns[column].BlendWith(baseColor)
+	}
+	return baseColor
+}
+
+// ZIndex
+func (this *Prompt) ZIndex() ZIndex {
+	return this.zindex
+}
+
+// Copy of the state of the prompt
+func (this *Prompt) Snapshot() interface{} {
+	return *this
+}
+
+// Go back to a state from Snapshot
+func (this *Prompt) Restore(snapshot interface{}) {
+	*this = snapshot.(Prompt)
+}
+
+// Animate, the text starts scrolling by again once it has left the field
+func (this *Prompt) Animate(dt float64) bool {
+	this.time += dt
+	this.ticker.time = math.Mod(this.time, this.ticker.Duration())
+	return true
+}
@@ -20,6 +20,7 @@ type Sinusoid struct {
 }
 
 var _ Drawable = &Sinusoid{}
+var _ Snapshotter = &Sinusoid{}
 
 // Construct a Sinusoid
 func NewSinusoid(field *GameField, zindex ZIndex) *Sinusoid {
@@ -72,6 +73,16 @@ func (this *Sinusoid) ZIndex() ZIndex {
 	return this.zindex
 }
 
+// Copy of the state of the animation
+func (this *Sinusoid) Snapshot() interface{} {
+	return *this
+}
+
+// Go back to a state from Snapshot
+func (this *Sinusoid) Restore(snapshot interface{}) {
+	*this = snapshot.(Sinusoid)
+}
+
 // Animate
 func (this *Sinusoid) Animate(dt float64) bool {
 
@@ -106,6 +117,7 @@ type HSLWheel struct {
 }
 
 var _ Drawable = &HSLWheel{}
+var _ Snapshotter = &HSLWheel{}
 
 // Construct an HSLWheel
 func NewHSLWheel(field *GameField, zindex ZIndex) *HSLWheel {
@@ -181,6 +193,16 @@ func (this *HSLWheel) ZIndex() ZIndex {
 	return this.zindex
 }
 
+// Copy of the state of the animation
+func (this *HSLWheel) Snapshot() interface{} {
+	return *this
+}
+
+// Go back to a state from Snapshot
+func (this *HSLWheel) Restore(snapshot interface{}) {
+	*this = snapshot.(HSLWheel)
+}
+
 // Animate
 func (this *HSLWheel) Animate(dt float64) bool {
 
@@ -217,6 +239,7 @@ type StepFunction struct {
 }
 
 var _ Drawable = &StepFunction{}
+var _ Snapshotter = &StepFunction{}
 
 // Construct a new StepFunction
 func NewStepFunction(center, stepSize float64, baseColor RGBA, zindex ZIndex) *StepFunction {
@@ -252,6 +275,16 @@ func (this *StepFunction) ZIndex() ZIndex {
 	return this.zindex
 }
 
+// Copy of the state of the animation
+func (this *StepFunction) Snapshot() interface{} {
+	return *this
+}
+
+// Go back to a state from Snapshot
+func (this *StepFunction) Restore(snapshot interface{}) {
+	*this = snapshot.(StepFunction)
+}
+
 // Animate
 func (this *StepFunction) Animate(dt float64) bool {
 
@@ -275,6 +308,7 @@ type Countdown struct {
 }
 
 var _ Drawable = &Countdown{}
+var _ Snapshotter = &Countdown{}
 
 // Construct a new StepFunction
 func NewCountdown(field *GameField, totalTime float64) *Countdown {
@@ -307,6 +341,16 @@ func (this *Countdown) ZIndex() ZIndex {
 	return 0
 }
 
+// Copy of the state of the countdown
+func (this *Countdown) Snapshot() interface{} {
+	return *this
+}
+
+// Go back to a state from Snapshot
+func (this *Countdown) Restore(snapshot interface{}) {
+	*this = snapshot.(Countdown)
+}
+
 // Animate
 func (this *Countdown) Animate(dt float64) bool {
 
@@ -341,11 +385,12 @@ type Winner struct {
 }
 
 var _ Drawable = &Winner{}
+var _ Snapshotter = &Winner{}
 
 // Construct a new StepFunction
 func NewWinner(field *GameField, leftWon bool, totalTime float64) *Winner {
 
-	if !leftWon {
+	if leftWon {
 		return &Winner{
 			time:      0.0,
 			totalTime: totalTime,
@@ -381,6 +426,16 @@ func (this *Winner) ZIndex() ZIndex {
 	return 0
 }
 
+// Copy of the state of the animation
+func (this *Winner) Snapshot() interface{} {
+	return *this
+}
+
+// Go back to a state from Snapshot
+func (this *Winner) Restore(snapshot interface{}) {
+	*this = snapshot.(Winner)
+}
+
 // Animate
 func (this *Winner) Animate(dt float64) bool {
 
@@ -397,3 +452,48 @@ func (this *Winner) Animate(dt float64) bool {
 func (this *Winner) TimeRemaining() float64 {
 	return this.totalTime - this.time
 }
+
+// Represents the top and bottom edges of a 2d field that the ball bounces off
+type Walls struct {
+
+	// bottom row, top row is 0
+	maxY float64
+
+	color RGBA
+
+	zindex ZIndex
+}
+
+var _ Drawable2D = &Walls{}
+
+// Construct Walls along the top and bottom rows of field
+func NewWalls(field *GameField, color RGBA, zindex ZIndex) *Walls {
+	return &Walls{
+		maxY:   float64(field.Height() - 1),
+		color:  color,
+		zindex: zindex,
+	}
+}
+
+// Walls have no width along a strip
+func (this *Walls) ColorAt(position float64, baseColor RGBA) RGBA {
+	return baseColor
+}
+
+// Returns the wall color on the top and bottom rows
+func (this *Walls) ColorAtXY(x, y float64, baseColor RGBA) RGBA {
+	if y == 0 || y == this.maxY {
+		return this.color.BlendWith(baseColor)
+	}
+	return baseColor
+}
+
+// ZIndex
+func (this *Walls) ZIndex() ZIndex {
+	return this.zindex
+}
+
+// Animate
+func (this *Walls) Animate(dt float64) bool {
+	return true
+}
@@ -2,10 +2,19 @@ package draw
 
 import (
 	"math"
-	"math/rand"
 	. "pong"
 )
 
+// How the ball was returned, set by the timing of the hit
+type Shot int
+
+const (
+	NormalShot Shot = iota
+	SmashShot
+	SoftShot
+	FakeOutShot
+)
+
 // Player that is drawn on the board
 type Ball struct {
 
@@ -18,23 +27,66 @@ type Ball struct {
 	// max position of ball, min is 0
 	maxPosition float64
 
+	// row of the ball and its speed across rows in leds / second, only used on a 2d field
+	y, velocityY float64
+
+	// bottom row of the field, 0 on a strip
+	maxY float64
+
+	// position and row before the last update, and where the ball is drawn between them
+	previousPosition, previousY float64
+	shownPosition, shownY       float64
+
+	// length of the last update, the ball is followed through it for hits
+	updateTime float64
+
+	// random numbers of the field, and the flicker of the tail picked from them on each update
+	random  *Random
+	flicker uint64
+
 	// the length of the tail of the ball
 	tailLength float64
 
 	// if the ball should be hidden this frame or not
 	hideBall bool
 
+	// player that hit the ball last, nil since it was served
+	hitBy *Player
+
+	// speed kept on the next hit from a slow ball power-up, 0 for none
+	slowNext float64
+
+	// time the ball stays invisible from a phantom ball power-up
+	phantomTime float64
+
+	// the ball left play and is removed from the field on the next update
+	out bool
+
+	// how the ball was last returned, the tail shows it, and how much that changed the speed along the field and across the rows, 0 for not at all
+	shot                 Shot
+	shotSpeed, shotAngle float64
+
+	// time a fake out turns the ball back for once it passes the middle, and the time left of turning back
+	fakeOut, turnedTime float64
+
 	// z position of ball
 	zindex ZIndex
 }
 
-var _ Drawable = &Ball{}
+var _ Drawable2D = &Ball{}
+var _ Interpolated = &Ball{}
+var _ Snapshotter = &Ball{}
 
-// Construct a Line
+// Construct a Ball served from a random side
 func NewBall(field *GameField) *Ball {
+	return NewBallServing(field, field.Random().Float64() <= 0.5)
+}
 
-	if rand.Float64() > 0.5 {
-		return &Ball{
+// Construct a Ball served by the left or right player
+func NewBallServing(field *GameField, leftServes bool) (ball *Ball) {
+
+	if !leftServes {
+		ball = &Ball{
 			position:    float64(field.Width()-1),
 			velocity:    -float64(field.Width()) / 2.0,
 			maxPosition: float64(field.Width() -1),
@@ -42,7 +94,7 @@ func NewBall(field *GameField) *Ball {
 			zindex:      100,
 		}
 	} else {
-		return &Ball{
+		ball = &Ball{
 			position:    0.0,
 			velocity:    float64(field.Width()) / 2.0,
 			maxPosition: float64(field.Width() - 1),
@@ -50,21 +102,70 @@ func NewBall(field *GameField) *Ball {
 			zindex:      100,
 		}
 	}
+
+	ball.random = field.Random()
+
+	// on a 2d field the ball also bounces between the top and bottom
+	if field.Height() > 1 {
+		ball.maxY = float64(field.Height() - 1)
+		ball.y = ball.random.Float64() * ball.maxY
+		ball.velocityY = (ball.random.Float64() - 0.5) * float64(field.Height())
+	}
+
+	ball.settle()
+	return
 }
 
 // Returns the color at position blended on top of baseColor
 func (this *Ball) ColorAt(position float64, baseColor RGBA) (color RGBA) {
 
-	distance := math.Abs(position - this.position)
+	if this.phantomTime > 0 {
+		return baseColor
+	}
+
+	distance := math.Abs(position - this.shownPosition)
 
 	// Add tail flame
-	if distance > 0.5 && distance < this.tailLength && ((this.position < position && this.velocity < 0) || (position < this.position && this.velocity > 0)) {
+	if distance > 0.5 && distance < this.tailLength && ((this.shownPosition < position && this.velocity < 0) || (position < this.shownPosition && this.velocity > 0)) {
+
+		tailColor := this.tailColor(uint8(Noise(this.flicker, position, 255)), uint8(((this.tailLength-distance)/this.tailLength)*255.0))
+		baseColor = tailColor.BlendWith(baseColor)
+	}
+
+	// Add ball itself as white
+	if !this.hideBall && distance < 1 {
+		color = RGBA{255, 255, 255, uint8((1.0 - distance) * 255.0)}
+		color = color.BlendWith(baseColor)
+	} else {
+		color = baseColor
+	}
+
+	return color
+}
+
+// Returns the color at x, y blended on top of baseColor, the tail follows the ball's path across rows
+func (this *Ball) ColorAtXY(x, y float64, baseColor RGBA) (color RGBA) {
+
+	if this.phantomTime > 0 {
+		return baseColor
+	}
 
-		tailColor := RGBA{255, uint8(rand.Intn(255)), 0, uint8(((this.tailLength - distance) / this.tailLength) * 255.0)}
+	dx, dy := x-this.shownPosition, y-this.shownY
+	speed := math.Hypot(this.velocity, this.velocityY)
+
+	// distance behind the ball along its path, and away from that path
+	behind := -(dx*this.velocity + dy*this.velocityY) / speed
+	aside := math.Abs(dx*this.velocityY-dy*this.velocity) / speed
+
+	// Add tail flame, narrowing towards its end
+	if behind > 0.5 && behind < this.tailLength && aside < 1.0 {
+		fade := ((this.tailLength - behind) / this.tailLength) * (1.0 - aside)
+		tailColor := this.tailColor(uint8(Noise(this.flicker+uint64(y), x, 255)), uint8(fade*255.0))
 		baseColor = tailColor.BlendWith(baseColor)
 	}
 
 	// Add ball itself as white
+	distance := math.Hypot(dx, dy)
 	if !this.hideBall && distance < 1 {
 		color = RGBA{255, 255, 255, uint8((1.0 - distance) * 255.0)}
 		color = color.BlendWith(baseColor)
@@ -75,50 +176,226 @@ func (this *Ball) ColorAt(position float64, baseColor RGBA) (color RGBA) {
 	return color
 }
 
+// Color of the tail flickering by flicker, a smash burns blue, a soft return red and a fake out purple
+func (this *Ball) tailColor(flicker, alpha uint8) RGBA {
+	switch this.shot {
+	case SmashShot:
+		return RGBA{flicker, flicker, 255, alpha}
+	case SoftShot:
+		return RGBA{255, flicker / 4, 0, alpha}
+	case FakeOutShot:
+		return RGBA{255, 0, flicker, alpha}
+	}
+	return RGBA{255, flicker, 0, alpha}
+}
+
 // ZIndex of the ball
 func (this *Ball) ZIndex() ZIndex {
 	return this.zindex
 }
 
+// Current position of the ball along the field
+func (this *Ball) Position() float64 {
+	return this.position
+}
+
+// Speed of the ball along the field in leds / second, negative while it moves towards the left player
+func (this *Ball) Velocity() float64 {
+	return this.velocity
+}
+
+// Speed of the ball in leds / second, along the field and across its rows
+func (this *Ball) Speed() float64 {
+	return math.Hypot(this.velocity, this.velocityY)
+}
+
+// Largest position of the ball, the smallest is 0
+func (this *Ball) MaxPosition() float64 {
+	return this.maxPosition
+}
+
+// Draw the ball where it is, without moving from where it was before
+func (this *Ball) settle() {
+	this.previousPosition, this.previousY = this.position, this.y
+	this.shownPosition, this.shownY = this.position, this.y
+}
+
+// Draw the ball alpha of the way from its position before the last update to its current one
+func (this *Ball) Interpolate(alpha float64) {
+	this.shownPosition = this.previousPosition + (this.position-this.previousPosition)*alpha
+	this.shownY = this.previousY + (this.y-this.previousY)*alpha
+}
+
+// Copy of the state of the ball
+func (this *Ball) Snapshot() interface{} {
+	return *this
+}
+
+// Go back to a state from Snapshot
+func (this *Ball) Restore(snapshot interface{}) {
+	*this = snapshot.(Ball)
+}
+
 // Animate ball
 func (this *Ball) Animate(dt float64) bool {
+	if this.out {
+		return false
+	}
+
+	this.phantomTime = math.Max(0, this.phantomTime-dt)
+	this.turnBack(dt)
+	this.previousPosition, this.previousY = this.position, this.y
+	this.updateTime = dt
+	this.flicker = this.random.Uint64()
+
 	this.position += this.velocity * dt
 
+	// bounce off the top and bottom of a 2d field
+	if this.maxY > 0 {
+		this.y += this.velocityY * dt
+		if this.y < 0 {
+			this.y = -this.y
+			this.velocityY = -this.velocityY
+		} else if this.y > this.maxY {
+			this.y = this.maxY - (this.y - this.maxY)
+			this.velocityY = -this.velocityY
+		}
+	}
+
+	this.shownPosition, this.shownY = this.position, this.y
 	return true
 }
 
-// Check if the ball went past a player, returns nil or the player that missed the ball
-func (this *Ball) MissedByPlayer(leftPlayer, rightPlayer *Player, bounceFactor float64) (missedPlayer *Player, hitBall bool) {
+// Turn the ball back for a moment once a fake out passes the middle of the field, then send it on again
+func (this *Ball) turnBack(dt float64) {
+	if this.fakeOut > 0 && (this.velocity > 0) == (this.position > this.maxPosition/2.0) {
+		this.velocity, this.turnedTime, this.fakeOut = -this.velocity, this.fakeOut, 0
+	} else if this.turnedTime > 0 {
+		this.turnedTime -= dt
+		if this.turnedTime <= 0 {
+			this.velocity = -this.velocity
+		}
+	}
+}
 
-	if this.velocity < 0 && this.position < leftPlayer.paddleRight {
+// Follow the ball through the last update and bounce it off each paddle that is held while the ball is in front of it,
+// any paddle on the side the ball heads to can hit it back. Returns the player with the outermost paddle of that side
+// when the ball got past every paddle there, or nil, and how many times the ball was hit back. The timing of each hit picks one of shots
+func (this *Ball) MissedByPlayer(players []*Player, bounceFactor float64, shots ShotSettings) (missedPlayer *Player, hits int) {
 
-		if !leftPlayer.paddleActive && this.position < leftPlayer.paddleLeft {
-			// player missed the ball
-			go PlaySound(MISS)
-			return leftPlayer, false
-		} else if leftPlayer.paddleActive {
-			// player hit the ball back
-			this.position = leftPlayer.paddleRight + (leftPlayer.paddleRight - this.position)
-			this.velocity = this.velocity * -bounceFactor
-			go PlaySound(LEFTBOUNCE)
-			return nil, true
+	at, position := 0.0, this.previousPosition
+	for at < this.updateTime && this.velocity != 0 {
+
+		// the first paddle that hits the ball back on the side it heads to, and the paddle it gets past last
+		var hitPlayer, lastPlayer *Player
+		hitAt, lastLeave := 0.0, math.Inf(-1)
+		for _, player := range players {
+			if player.IsLeft() != (this.velocity < 0) {
+				continue
+			}
+
+			// near is the edge facing the field
+			near, far := player.paddleLeft, player.paddleRight
+			if this.velocity < 0 {
+				near, far = player.paddleRight, player.paddleLeft
+			}
+
+			// times the ball reaches the near edge and goes past the far edge, at the latest the end of the update
+			enter := at + math.Max(0, (near-position)/this.velocity)
+			leave := at + math.Max(0, (far-position)/this.velocity)
+			if leave > lastLeave {
+				lastPlayer, lastLeave = player, leave
+			}
+
+			// a paddle the ball already got past can't hit it anymore
+			if (far-position)/this.velocity < 0 || enter >= this.updateTime {
+				continue
+			}
+			if playerHitAt, ok := player.firstActive(enter, math.Min(leave, this.updateTime)); ok && (hitPlayer == nil || playerHitAt < hitAt) {
+				hitPlayer, hitAt = player, playerHitAt
+			}
+		}
+
+		if hitPlayer != nil {
+			// player hit the ball back where it was at that moment
+			sound := SoundType(RIGHTBOUNCE)
+			if this.velocity < 0 {
+				sound = LEFTBOUNCE
+			}
+			position += this.velocity * (hitAt - at)
+			at = hitAt
+			if hitPlayer.bounceFactor > 0 {
+				this.velocity = this.velocity * -hitPlayer.bounceFactor
+			} else {
+				this.velocity = this.velocity * -bounceFactor
+			}
+			if this.slowNext > 0 {
+				this.velocity *= this.slowNext
+				this.slowNext = 0
+			}
+			held, sincePrevious := hitPlayer.pushTiming(hitAt)
+			this.takeShot(held, sincePrevious, shots)
+			this.hitBy = hitPlayer
+			hits++
+			go PlaySound(sound)
+			continue
 		}
-	} else if this.velocity > 0 && this.position > rightPlayer.paddleLeft {
 
-		if !rightPlayer.paddleActive && this.position > rightPlayer.paddleRight {
-			// player missed the ball
+		if lastPlayer != nil && lastLeave < this.updateTime {
+			// every player of the side missed the ball
+			this.position = position + this.velocity*(this.updateTime-at)
+			this.shownPosition = this.position
 			go PlaySound(MISS)
-			return rightPlayer, false
-		} else if rightPlayer.paddleActive {
-			// player hit the ball back
-			this.position = rightPlayer.paddleLeft - (this.position - rightPlayer.paddleLeft)
-			this.velocity = this.velocity * -bounceFactor
-			go PlaySound(RIGHTBOUNCE)
-			return nil, true
+			return lastPlayer, hits
 		}
+		break
 	}
 
-	return nil, false
+	this.position = position + this.velocity*(this.updateTime-at)
+	this.shownPosition = this.position
+	return nil, hits
+}
+
+// Change the return by the timing of the hit, held is how long the paddle had been held and sincePrevious the time since the push before
+func (this *Ball) takeShot(held, sincePrevious float64, shots ShotSettings) {
+
+	this.plainShot()
+
+	speed, angle := 1.0, 1.0
+	switch {
+	case shots.DoubleTap > 0 && sincePrevious <= shots.DoubleTap:
+		this.shot, this.fakeOut = FakeOutShot, shots.FakeOut
+		return
+	case shots.Smash > 0 && held <= shots.Smash:
+		this.shot, speed, angle = SmashShot, shots.SmashSpeed, shots.SmashAngle
+	case shots.Soft > 0 && held > shots.Soft:
+		this.shot, speed, angle = SoftShot, shots.SoftSpeed, shots.SoftAngle
+	default:
+		return
+	}
+
+	this.velocity *= speed
+
+	// never steeper across the rows than along the field
+	velocityY := math.Copysign(math.Min(math.Abs(this.velocityY*angle), math.Abs(this.velocity)), this.velocityY)
+
+	this.shotSpeed, this.shotAngle = speed, 0
+	if this.velocityY != 0 {
+		this.shotAngle = velocityY / this.velocityY
+	}
+	this.velocityY = velocityY
+}
+
+// Undo the changes of the last shot, each one only changes the return it was hit with
+func (this *Ball) plainShot() {
+	if this.shotSpeed != 0 {
+		this.velocity /= this.shotSpeed
+	}
+	if this.shotAngle != 0 {
+		this.velocityY /= this.shotAngle
+	}
+	this.shot, this.shotSpeed, this.shotAngle = NormalShot, 0, 0
+	this.fakeOut, this.turnedTime = 0, 0
 }
 
 // Reset the position to the middle of the field
@@ -130,15 +407,57 @@ func (this *Ball) ResetPosition(field *GameField) {
 	}
 
 	this.position = float64(field.Width()) * startingOffset
+	this.hitBy, this.slowNext, this.phantomTime = nil, 0, 0
+	this.plainShot()
+	this.settle()
 }
 
-// Check if the player is doing an offensive hide
-func (this *Ball) UpdateOffensiveHide(leftPlayer, rightPlayer *Player) {
+// Player that hit the ball last, nil since it was served
+func (this *Ball) HitBy() *Player {
+	return this.hitBy
+}
+
+// Take the ball out of play, it leaves the field on the next update
+func (this *Ball) Remove() {
+	this.out = true
+}
+
+// Balls split off this one at the same place, going slower and faster in turn and across the rows the other way
+func (this *Ball) Split(count int) (balls []*Ball) {
+	for index := 1; index <= count; index++ {
+		ball := *this
+		change := 0.2 * float64((index+1)/2)
+		if index%2 == 1 {
+			change = -change
+			ball.velocityY = -ball.velocityY
+		}
+		ball.velocity *= 1.0 + change
+		balls = append(balls, &ball)
+	}
+	return
+}
+
+// Show the players the ball heads to how far in front of their paddle a push smashes it
+func (this *Ball) ShowSmashZone(players []*Player, shots ShotSettings) {
+	for _, player := range players {
+		if player.IsLeft() == (this.velocity < 0) {
+			player.showSmashZone(shots.Smash * math.Abs(this.velocity))
+		}
+	}
+}
+
+// Check if any of the players is doing an offensive hide, holding the paddle while the ball is on the other side heading away
+func (this *Ball) UpdateOffensiveHide(players []*Player) {
 
 	this.hideBall = false
-	if this.velocity < 0 && this.position < this.maxPosition/2.0 && rightPlayer.paddleActive {
-		this.hideBall = true
-	} else if this.velocity > 0 && this.position > this.maxPosition/2.0 && leftPlayer.paddleActive {
-		this.hideBall = true
+	for _, player := range players {
+		if !player.paddleActive {
+			continue
+		}
+		if player.IsLeft() && this.velocity > 0 && this.position > this.maxPosition/2.0 {
+			this.hideBall = true
+		} else if !player.IsLeft() && this.velocity < 0 && this.position < this.maxPosition/2.0 {
+			this.hideBall = true
+		}
 	}
 }
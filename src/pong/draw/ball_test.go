@@ -0,0 +1,314 @@
+package draw
+
+import (
+	"math"
+	. "pong"
+	"testing"
+)
+
+// 1 for true, 0 for false, to assert bools
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// Ball on field at position heading along it at velocity
+func newTestBall(field *GameField, position, velocity float64) *Ball {
+	ball := NewBallServing(field, true)
+	ball.position, ball.velocity = position, velocity
+	ball.settle()
+	return ball
+}
+
+// One update of the game, the players move before the ball checks for hits as on the field
+func stepBall(dt float64, ball *Ball, players ...*Player) (missedPlayer *Player, hits int) {
+	for _, player := range players {
+		player.Animate(dt)
+	}
+	ball.Animate(dt)
+	return ball.MissedByPlayer(players, 1, ShotSettings{})
+}
+
+// Changes are applied in the order of their time, those after the update wait for the next one
+func Test_Player_UpdatePaddleActiveAt(t *testing.T) {
+	player := NewPlayer(false, 10, newTestField(1))
+
+	player.UpdatePaddleActiveAt(true, 0.35)
+	player.UpdatePaddleActiveAt(false, 0.15)
+	player.UpdatePaddleActiveAt(true, 0.05)
+	player.Animate(0.25)
+
+	at, ok := player.firstActive(0, 0.25)
+	Assert(boolToInt(ok), 1, "Held during the first update", t)
+	assertNear(at, 0.05, "Pushed", t)
+	at, _ = player.firstActive(0.1, 0.25)
+	assertNear(at, 0.1, "Held from the start of the check", t)
+	_, ok = player.firstActive(0.15, 0.25)
+	Assert(boolToInt(ok), 0, "Held after the release", t)
+
+	player.Animate(0.25)
+	at, ok = player.firstActive(0, 0.25)
+	Assert(boolToInt(ok), 1, "Held during the second update", t)
+	assertNear(at, 0.1, "Push carried over", t)
+	assertNear(player.pushes[0], 0.35, "Latest push", t)
+	assertNear(player.pushes[1], 0.05, "Push before", t)
+}
+
+// The ball is in front of the right paddle from 0.175 to 0.225 into the update, a push at 0.2 returns it from where it was then
+func Test_Ball_MissedByPlayer_PushInTime(t *testing.T) {
+	field := newTestField(1)
+	player := NewPlayer(false, 10, field)
+	ball := newTestBall(field, 55, 20)
+
+	player.UpdatePaddleActiveAt(true, 0.2)
+	missed, hits := stepBall(0.25, ball, player)
+
+	if missed != nil {
+		t.Fatal("Missed a ball in front of the paddle")
+	}
+	Assert(hits, 1, "Hits", t)
+	assertNear(ball.Velocity(), -20, "Velocity after the hit", t)
+	assertNear(ball.Position(), 58, "Position after the hit", t)
+	if ball.HitBy() != player {
+		t.Fatal("Hit by another player")
+	}
+}
+
+// A push after the ball went past the paddle misses it, the ball goes on out of the field
+func Test_Ball_MissedByPlayer_PushTooLate(t *testing.T) {
+	field := newTestField(1)
+	player := NewPlayer(false, 10, field)
+	ball := newTestBall(field, 55, 20)
+
+	player.UpdatePaddleActiveAt(true, 0.23)
+	missed, hits := stepBall(0.25, ball, player)
+
+	if missed != player {
+		t.Fatal("Miss not reported")
+	}
+	Assert(hits, 0, "Hits", t)
+	assertNear(ball.Position(), 60, "Position after the miss", t)
+}
+
+// A fast ball in a long update bounces between both held paddles several times
+func Test_Ball_MissedByPlayer_SeveralBounces(t *testing.T) {
+	field := newTestField(1)
+	left, right := NewPlayer(true, 10, field), NewPlayer(false, 10, field)
+	ball := newTestBall(field, 30, 100)
+
+	left.UpdatePaddleActive(true)
+	right.UpdatePaddleActive(true)
+	missed, hits := stepBall(2, ball, left, right)
+
+	if missed != nil {
+		t.Fatal("Missed by", missed)
+	}
+
+	// returned at 58.5, 0.5 and 58.5 again, then 0.555 seconds towards the left
+	Assert(hits, 3, "Hits", t)
+	assertNear(ball.Velocity(), -100, "Velocity", t)
+	assertNear(ball.Position(), 3, "Position", t)
+	if ball.HitBy() != right {
+		t.Fatal("Last hit by the left player")
+	}
+}
+
+// A paddle running out of life drops at the moment the life is used up, balls arriving later get past it
+func Test_Player_HoldPaddle_LifeRunsOut(t *testing.T) {
+	field := newTestField(1)
+	player := NewPlayer(false, 0.1, field)
+	ball := newTestBall(field, 55, 20)
+
+	player.UpdatePaddleActive(true)
+	missed, _ := stepBall(0.25, ball, player)
+
+	Assert(boolToInt(player.paddleActive), 0, "Held without life", t)
+	assertNear(player.Life(), 0, "Life", t)
+	Assert(len(player.stepChanges), 1, "Changes", t)
+	assertNear(player.stepChanges[0].at, 0.1, "Dropped", t)
+	if _, ok := player.firstActive(0.1, 0.25); ok {
+		t.Fatal("Held after the life ran out")
+	}
+	if missed != player {
+		t.Fatal("Ball arriving at 0.175 returned")
+	}
+
+	// a ball arriving before the life runs out is still returned, twice the drain uses it up in half the time
+	player = NewPlayer(false, 0.1, field)
+	player.Handicap(0, 0, 2, 0)
+	ball = newTestBall(field, 58, 20)
+
+	player.UpdatePaddleActive(true)
+	missed, hits := stepBall(0.25, ball, player)
+
+	assertNear(player.stepChanges[0].at, 0.05, "Dropped with twice the drain", t)
+	if missed != nil || hits != 1 {
+		t.Fatal("Ball arriving at 0.025 missed")
+	}
+}
+
+// In doubles the inner paddle returns the ball first, a ball that gets past both is missed by the outer player
+func Test_Ball_MissedByPlayer_Doubles(t *testing.T) {
+	field := newTestField(1)
+	leftOuter, leftInner := NewDoublesPlayers(true, 6, 10, field)
+	rightOuter, rightInner := NewDoublesPlayers(false, 6, 10, field)
+	players := []*Player{leftOuter, leftInner, rightOuter, rightInner}
+
+	// the inner paddle at 53 is reached at 0.225 and the outer one is held too
+	ball := newTestBall(field, 48, 20)
+	rightInner.UpdatePaddleActive(true)
+	rightOuter.UpdatePaddleActive(true)
+	missed, hits := stepBall(0.25, ball, players...)
+
+	if missed != nil || hits != 1 {
+		t.Fatal("Inner paddle missed", missed, hits)
+	}
+	if ball.HitBy() != rightInner {
+		t.Fatal("Not hit by the inner player")
+	}
+	assertNear(ball.Position(), 52, "Position after the hit", t)
+
+	// past both paddles of the left side while nobody holds them
+	ball = newTestBall(field, 15, -80)
+	missed, hits = stepBall(0.25, ball, players...)
+	if missed != leftOuter {
+		t.Fatal("Miss not reported for the outer player")
+	}
+	Assert(hits, 0, "Hits", t)
+}
+
+// Life bars of doubles on a narrow field can be a single led, the side comes from the constructor
+func Test_Player_IsLeft_NarrowField(t *testing.T) {
+	field := NewGameField(4)
+	leftOuter, leftInner := NewDoublesPlayers(true, 1, 10, field)
+	rightOuter, rightInner := NewDoublesPlayers(false, 1, 10, field)
+
+	for _, player := range []*Player{leftOuter, leftInner} {
+		Assert(boolToInt(player.IsLeft()), 1, "Left player", t)
+	}
+	for _, player := range []*Player{rightOuter, rightInner} {
+		Assert(boolToInt(player.IsLeft()), 0, "Right player", t)
+	}
+}
+
+// shots as in the example settings
+var testShots = ShotSettings{Smash: 0.04, SmashSpeed: 1.25, SmashAngle: 1.5, Soft: 0.3, SoftSpeed: 0.8, SoftAngle: 0.5, DoubleTap: 0.25, FakeOut: 0.15}
+
+// The timing of the push picks the shot, a plain shot undoes it again
+func Test_Ball_TakeShot(t *testing.T) {
+	inf := math.Inf(1)
+	for _, check := range []struct {
+		name                 string
+		shots                ShotSettings
+		velocityY            float64
+		held, sincePrevious  float64
+		shot                 Shot
+		velocity, velocityYs float64
+		fakeOut              float64
+	}{
+		{"smash", testShots, 4, 0.02, inf, SmashShot, 25, 6, 0},
+		{"steep smash", testShots, 18, 0.02, inf, SmashShot, 25, 25, 0},
+		{"soft", testShots, 4, 0.5, inf, SoftShot, 16, 2, 0},
+		{"fake out", testShots, 4, 0.1, 0.2, FakeOutShot, 20, 4, 0.15},
+		{"fake out before smash", testShots, 4, 0.02, 0.2, FakeOutShot, 20, 4, 0.15},
+		{"plain", testShots, 4, 0.1, 1, NormalShot, 20, 4, 0},
+		{"shots off", ShotSettings{}, 4, 0.02, 0.2, NormalShot, 20, 4, 0},
+	} {
+		ball := &Ball{velocity: 20, velocityY: check.velocityY}
+
+		ball.takeShot(check.held, check.sincePrevious, check.shots)
+		Assert(int(ball.shot), int(check.shot), check.name+" shot", t)
+		assertNear(ball.velocity, check.velocity, check.name+" velocity", t)
+		assertNear(ball.velocityY, check.velocityYs, check.name+" velocity across the rows", t)
+		assertNear(ball.fakeOut, check.fakeOut, check.name+" fake out", t)
+
+		ball.plainShot()
+		Assert(int(ball.shot), int(NormalShot), check.name+" plain shot", t)
+		assertNear(ball.velocity, 20, check.name+" plain velocity", t)
+		assertNear(ball.velocityY, check.velocityY, check.name+" plain velocity across the rows", t)
+		assertNear(ball.fakeOut, 0, check.name+" plain fake out", t)
+	}
+
+	// each shot only changes the return it was hit with
+	ball := &Ball{velocity: 20, velocityY: 4}
+	ball.takeShot(0.02, inf, testShots)
+	ball.takeShot(0.5, inf, testShots)
+	assertNear(ball.velocity, 16, "Soft after a smash", t)
+	assertNear(ball.velocityY, 2, "Soft after a smash across the rows", t)
+}
+
+// A fake out turns the ball back once it passes the middle of the field for FakeOut seconds
+func Test_Ball_TurnBack(t *testing.T) {
+	for _, check := range []struct {
+		name               string
+		position, velocity float64
+		steps, velocities  []float64
+	}{
+		{"before the middle", 20, 20, []float64{0.1}, []float64{20}},
+		{"right", 31, 20, []float64{0.1, 0.1, 0.1}, []float64{-20, -20, 20}},
+		{"left", 29, -20, []float64{0.1, 0.1, 0.1}, []float64{20, 20, -20}},
+		{"heading back", 31, -20, []float64{0.1}, []float64{-20}},
+	} {
+		ball := &Ball{position: check.position, velocity: check.velocity, maxPosition: 60, fakeOut: 0.15}
+		for step, dt := range check.steps {
+			ball.turnBack(dt)
+			assertNear(ball.velocity, check.velocities[step], check.name+" velocity", t)
+		}
+	}
+}
+
+// Hits are timed from the latest push before them and the push before that
+func Test_Player_PushTiming(t *testing.T) {
+	inf, none := math.Inf(1), math.Inf(-1)
+	for _, check := range []struct {
+		name                string
+		pushes              [3]float64
+		at                  float64
+		held, sincePrevious float64
+	}{
+		{"latest push", [3]float64{10.1, 9.9, 9}, 0.2, 0.1, 0.2},
+		{"push after the hit", [3]float64{10.1, 9.9, 9}, 0.05, 0.15, 0.9},
+		{"single push", [3]float64{10.1, none, none}, 0.2, 0.1, inf},
+		{"oldest push", [3]float64{10.1, 10.05, 9}, 0.02, 1.02, inf},
+		{"never pushed", [3]float64{none, none, none}, 0.2, inf, inf},
+	} {
+		player := &Player{stepStart: 10, pushes: check.pushes}
+		held, sincePrevious := player.pushTiming(check.at)
+		if held != check.held && math.Abs(held-check.held) > 1e-9 {
+			t.Fatal(check.name, "held", held, "vs expected", check.held)
+		}
+		if sincePrevious != check.sincePrevious && math.Abs(sincePrevious-check.sincePrevious) > 1e-9 {
+			t.Fatal(check.name, "since the previous push", sincePrevious, "vs expected", check.sincePrevious)
+		}
+	}
+
+	// pushes during an update are timed at the moment they happened
+	player := NewPlayer(false, 10, newTestField(1))
+	player.UpdatePaddleActiveAt(true, 0.1)
+	player.UpdatePaddleActiveAt(false, 0.15)
+	player.UpdatePaddleActiveAt(true, 0.2)
+	player.Animate(0.25)
+	held, sincePrevious := player.pushTiming(0.22)
+	assertNear(held, 0.02, "Held", t)
+	assertNear(sincePrevious, 0.1, "Since the previous push", t)
+}
+
+// The paddles the ball heads to show where a push smashes it, the faster the ball the wider
+func Test_Ball_ShowSmashZone(t *testing.T) {
+	field := newTestField(1)
+	left, right := NewPlayer(true, 10, field), NewPlayer(false, 10, field)
+	players := []*Player{left, right}
+
+	ball := &Ball{velocity: 50}
+	ball.ShowSmashZone(players, testShots)
+	assertNear(left.smashZone, 0, "Smash zone of the player the ball heads away from", t)
+	assertNear(right.smashZone, 2, "Smash zone", t)
+
+	(&Ball{velocity: 25}).ShowSmashZone(players, testShots)
+	assertNear(right.smashZone, 2, "Smash zone of the fastest ball", t)
+
+	right.Animate(0.1)
+	assertNear(right.smashZone, 0, "Smash zone after an update", t)
+}
@@ -1,16 +1,21 @@
 package draw
 
 import (
+	"math"
 	. "pong"
 )
 
 // Player that is drawn on the board
 type Player struct {
 
+	// side of the field the player defends
+	isLeft bool
+
 	// start and end of life bar, start is at 0 life, end is full life
 	start, end float64
 
-	// bounds of the paddle, used for collision detection
+	// led the paddle is drawn on and its bounds, used for collision detection
+	paddle                  float64
 	paddleLeft, paddleRight float64
 
 	// colors that the different parts of the player are drawn
@@ -22,59 +27,217 @@ type Player struct {
 	// if the player is current holding down the button
 	paddleActive bool
 
+	// changes of the paddle that happen during the coming update, in order of their time after its start
+	pending []paddleChange
+
+	// the paddle during the last update, held at its start and changed at times after its start
+	stepStartActive bool
+	stepChanges     []paddleChange
+
 	// amount of life left
 	life, lifeTotal float64
 
+	// life used up for each second the paddle is held, and the speed-up of the balls the player returns, 0 for the field's
+	drain, bounceFactor float64
+
 	// current amount of animation, goes from 0 to 1 and back
 	lifeAnimation float64
+
+	// time left of a wide paddle power-up and how far it widens the paddle into the field
+	wideTime, wideLeds float64
+
+	// time since the player was created at the start of the last update and at its end
+	stepStart, clock float64
+
+	// times of the latest pushes of the paddle, the latest first
+	pushes [3]float64
+
+	// leds in front of the paddle the ball is smashed from when it's pushed now, 0 while the ball heads away
+	smashZone float64
+}
+
+// The paddle being pushed or released some time into an update
+type paddleChange struct {
+	at     float64
+	active bool
 }
 
 // rate at which lifeAnimation changes
 var lifeAnimationRate float64 = 0.25
 
 var testPlayer Drawable = &Player{}
+var _ Snapshotter = &Player{}
 
 // Construct a Line
 func NewPlayer(isLeft bool, lifeTime float64, field *GameField) (player *Player) {
 
+	color := RGBA{0, 255, 0, 255}
 	if isLeft {
-		player = &Player{
-			lifeColor:   RGBA{0, 0, 255, 150},
-			paddleColor: RGBA{0, 0, 255, 255},
-			zindex:      10,
-			start:       0.0,
-			end:         (float64(field.Width()) / 2.0) - 1,
-			paddleLeft:  -0.5,
-			paddleRight: 0.5,
-			life:        lifeTime,
-			lifeTotal:   lifeTime,
-		}
-	} else {
-		player = &Player{
-			lifeColor:   RGBA{0, 255, 0, 150},
-			paddleColor: RGBA{0, 255, 0, 255},
-			zindex:      10,
-			start:       float64(field.Width()) - 1.0,
-			end:         (float64(field.Width()) / 2.0),
-			paddleLeft:  float64(field.Width()) - 1.5,
-			paddleRight: float64(field.Width()) - 0.5,
-			life:        lifeTime,
-			lifeTotal:   lifeTime,
-		}
+		color = RGBA{0, 0, 255, 255}
+	}
+	return NewTeamPlayer(isLeft, 0, 0, 1, color, lifeTime, field)
+}
+
+// Construct the two players of a side in doubles, the inner one has a purple or yellow paddle depth leds in from the end
+func NewDoublesPlayers(isLeft bool, depth float64, lifeTime float64, field *GameField) (outer, inner *Player) {
+
+	outerColor, innerColor := RGBA{0, 255, 0, 255}, RGBA{200, 255, 0, 255}
+	if isLeft {
+		outerColor, innerColor = RGBA{0, 0, 255, 255}, RGBA{170, 0, 255, 255}
+	}
+	return NewTeamPlayer(isLeft, 0, 0, 2, outerColor, lifeTime, field), NewTeamPlayer(isLeft, depth, 1, 2, innerColor, lifeTime, field)
+}
+
+// Construct one of the players of a side in doubles, with the paddle depth leds in from the end of the field.
+// The side's half of the field is split between its players' life bars, player number share gets the share-th part counted from the end
+func NewTeamPlayer(isLeft bool, depth float64, share, shares int, color RGBA, lifeTime float64, field *GameField) (player *Player) {
+
+	half := float64(field.Width()) / 2.0
+	barStart := half * float64(share) / float64(shares)
+	barEnd := half*float64(share+1)/float64(shares) - 1
+
+	player = &Player{
+		isLeft:      isLeft,
+		lifeColor:   RGBA{color.R, color.G, color.B, 150},
+		paddleColor: color,
+		zindex:      ZIndex(10 + share),
+		start:       barStart,
+		end:         barEnd,
+		paddle:      depth,
+		life:        lifeTime,
+		lifeTotal:   lifeTime,
+		drain:       1,
+		pushes:      [3]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)},
+	}
+
+	// the right side mirrors the left
+	if !isLeft {
+		last := float64(field.Width()) - 1.0
+		player.start, player.end, player.paddle = last-barStart, last-barEnd, last-depth
 	}
+	player.paddleLeft, player.paddleRight = player.paddle-0.5, player.paddle+0.5
 
 	return
 }
 
+// Handicap the player with life to start with, a paddle width leds wide, drain life used up for each second it's held
+// and the bounceFactor of its returns, 0 keeps the default of each
+func (this *Player) Handicap(life, width, drain, bounceFactor float64) {
+	if life > 0 {
+		this.life, this.lifeTotal = life, life
+	}
+	if width > 0 {
+		this.widen(width - (this.paddleRight - this.paddleLeft))
+	}
+	if drain > 0 {
+		this.drain = drain
+	}
+	this.bounceFactor = bounceFactor
+}
+
+// Draw the paddle and life bar in color
+func (this *Player) SetColor(color RGBA) {
+	this.paddleColor = color
+	this.lifeColor = RGBA{color.R, color.G, color.B, 150}
+}
+
 // Set if the player is holding down the paddle or not
 func (this *Player) UpdatePaddleActive(paddleActive bool) {
+	paddleActive = paddleActive && this.life > 0.0
+
+	// the push happens at the start of the next update
+	if paddleActive && !this.paddleActive {
+		this.pushed(this.clock)
+	}
 	this.paddleActive = paddleActive
+}
+
+// Remember a push of the paddle at time
+func (this *Player) pushed(time float64) {
+	copy(this.pushes[1:], this.pushes[:])
+	this.pushes[0] = time
+}
+
+// How long the paddle had been held at at into the last update, and the time between that push and the one before
+func (this *Player) pushTiming(at float64) (held, sincePrevious float64) {
+
+	time := this.stepStart + at
+	for index, pushed := range this.pushes {
+		if pushed > time {
+			continue
+		}
+		if math.IsInf(pushed, -1) {
+			break
+		}
+		held, sincePrevious = time-pushed, math.Inf(1)
+		if index+1 < len(this.pushes) {
+			sincePrevious = pushed - this.pushes[index+1]
+		}
+		return
+	}
+	return math.Inf(1), math.Inf(1)
+}
+
+// Show the ball would be smashed from leds in front of the paddle, the widest zone of the balls heading here is shown
+func (this *Player) showSmashZone(leds float64) {
+	this.smashZone = max(this.smashZone, leds)
+}
+
+// Set if the player is holding down the paddle delay seconds into the next Animate, so hits are checked at the moment of the push
+func (this *Player) UpdatePaddleActiveAt(paddleActive bool, delay float64) {
 
-	if this.life <= 0.0 {
+	if delay <= 0 {
+		this.UpdatePaddleActive(paddleActive)
+		return
+	}
+
+	index := len(this.pending)
+	for index > 0 && this.pending[index-1].at > delay {
+		index--
+	}
+	this.pending = append(this.pending, paddleChange{})
+	copy(this.pending[index+1:], this.pending[index:])
+	this.pending[index] = paddleChange{at: delay, active: paddleActive}
+}
+
+// First time from start to end into the last update the paddle was held, false if it wasn't
+func (this *Player) firstActive(start, end float64) (at float64, ok bool) {
+
+	active, from := this.stepStartActive, 0.0
+	for _, change := range this.stepChanges {
+		if active && change.at > start && from <= end {
+			return max(from, start), true
+		}
+		active, from = change.active, change.at
+	}
+
+	if active && from <= end {
+		return max(from, start), true
+	}
+	return 0, false
+}
+
+// Use up life while the paddle is held for duration, from start into the update
+func (this *Player) holdPaddle(start, duration float64) {
+
+	if !this.paddleActive {
+		return
+	}
+
+	this.life -= duration * this.drain
+	if this.life < 0.0 {
+		// out of life part of the way through
+		this.stepChanges = append(this.stepChanges, paddleChange{at: start + duration + this.life/this.drain, active: false})
+		this.life = 0.0
 		this.paddleActive = false
 	}
 }
 
+// True for the players on the left side of the field
+func (this *Player) IsLeft() bool {
+	return this.isLeft
+}
+
 // Returns the color at position blended on top of baseColor
 func (this *Player) ColorAt(position float64, baseColor RGBA) (color RGBA) {
 
@@ -83,7 +246,7 @@ func (this *Player) ColorAt(position float64, baseColor RGBA) (color RGBA) {
 	left := min(this.start, lifeBarEnd)
 	right := max(this.start, lifeBarEnd)
 
-	if this.paddleActive && position == this.start {
+	if this.paddleActive && this.paddleLeft < position && position < this.paddleRight {
 		color = this.paddleColor.BlendWith(baseColor)
 	} else if left <= position && position <= right && this.life > 0 {
 
@@ -107,14 +270,45 @@ func (this *Player) ColorAt(position float64, baseColor RGBA) (color RGBA) {
 		color = baseColor
 	}
 
+	// where a push smashes the ball shows over the life bar
+	if this.inSmashZone(position) {
+		color = RGBA{255, 255, 255, 60}.BlendWith(color)
+	}
+
 	return
 }
 
+// True in front of the paddle where a push smashes the ball
+func (this *Player) inSmashZone(position float64) bool {
+	if this.smashZone <= 0 {
+		return false
+	}
+	if this.IsLeft() {
+		return this.paddleRight <= position && position < this.paddleRight+this.smashZone
+	}
+	return this.paddleLeft-this.smashZone < position && position <= this.paddleLeft
+}
+
 // ZIndex of the player
 func (this *Player) ZIndex() ZIndex {
 	return this.zindex
 }
 
+// Copy of the state of the player
+func (this *Player) Snapshot() interface{} {
+	snapshot := *this
+	snapshot.pending = append([]paddleChange(nil), this.pending...)
+	snapshot.stepChanges = append([]paddleChange(nil), this.stepChanges...)
+	return snapshot
+}
+
+// Go back to a state from Snapshot
+func (this *Player) Restore(snapshot interface{}) {
+	*this = snapshot.(Player)
+	this.pending = append([]paddleChange(nil), this.pending...)
+	this.stepChanges = append([]paddleChange(nil), this.stepChanges...)
+}
+
 // Animate player
 func (this *Player) Animate(dt float64) bool {
 
@@ -123,17 +317,73 @@ func (this *Player) Animate(dt float64) bool {
 		this.lifeAnimation -= 1.0
 	}
 
-	if this.paddleActive {
-		this.life -= dt
-		if this.life < 0.0 {
-			this.life = 0.0
-			this.paddleActive = false
+	if this.wideTime > 0 {
+		this.wideTime -= dt
+		if this.wideTime <= 0 {
+			this.widen(-this.wideLeds)
+			this.wideTime, this.wideLeds = 0, 0
+		}
+	}
+
+	this.stepStartActive = this.paddleActive
+	this.stepChanges = this.stepChanges[:0]
+	this.stepStart, this.clock = this.clock, this.clock+dt
+	this.smashZone = 0
+
+	// apply the pushes and releases that happened during this update at their time, later ones wait for the next
+	at := 0.0
+	var later []paddleChange
+	for _, change := range this.pending {
+		if change.at >= dt {
+			later = append(later, paddleChange{at: change.at - dt, active: change.active})
+			continue
+		}
+
+		this.holdPaddle(at, change.at-at)
+		at = change.at
+
+		if active := change.active && this.life > 0.0; active != this.paddleActive {
+			this.paddleActive = active
+			this.stepChanges = append(this.stepChanges, paddleChange{at: at, active: active})
+			if active {
+				this.pushed(this.stepStart + at)
+			}
 		}
 	}
+	this.holdPaddle(at, dt-at)
+	this.pending = later
 
 	return true
 }
 
+// Widen the paddle leds further into the field for seconds, a paddle that's wide already only stays so longer
+func (this *Player) Widen(leds, seconds float64) {
+	if this.wideTime <= 0 {
+		this.wideLeds = leds
+		this.widen(leds)
+	}
+	this.wideTime = seconds
+}
+
+// Move the edge of the paddle facing the field by leds
+func (this *Player) widen(leds float64) {
+	if this.IsLeft() {
+		this.paddleRight += leds
+	} else {
+		this.paddleLeft -= leds
+	}
+}
+
+// Give back a fraction of the full life, at most up to full
+func (this *Player) Refill(fraction float64) {
+	this.life = min(this.lifeTotal, this.life+this.lifeTotal*fraction)
+}
+
+// Seconds of life left
+func (this *Player) Life() float64 {
+	return this.life
+}
+
 // Decrease the amount of life remaining
 func (this *Player) DecreaseLife(dt float64) bool {
 	this.life -= dt
@@ -0,0 +1,198 @@
+package draw
+
+import (
+	"fmt"
+	"math"
+	. "pong"
+	"strings"
+)
+
+// Effect of a power-up on the side that collects it, named in settings
+type PowerUpKind string
+
+const (
+	WidePaddle  PowerUpKind = "wide"      // the paddles of the side reach further into the field for a while
+	SlowBall    PowerUpKind = "slow"      // the next return of the opponent comes back slower
+	LifeRefill  PowerUpKind = "life"      // the players of the side get some of their life back
+	PhantomBall PowerUpKind = "phantom"   // the ball can't be seen for a while
+	MultiBall   PowerUpKind = "multiball" // the ball splits into several that all have to be returned
+)
+
+// Every kind of power-up, in the order they're picked from
+var PowerUpKinds = []PowerUpKind{WidePaddle, SlowBall, LifeRefill, PhantomBall, MultiBall}
+
+// how each kind of power-up is drawn, and how it's announced
+var powerUpColors = map[PowerUpKind]RGBA{
+	WidePaddle:  {255, 0, 255, 255},
+	SlowBall:    {0, 255, 255, 255},
+	LifeRefill:  {255, 40, 40, 255},
+	PhantomBall: {120, 120, 120, 255},
+	MultiBall:   {255, 255, 0, 255},
+}
+
+var powerUpNames = map[PowerUpKind]string{
+	WidePaddle:  "wide paddle",
+	SlowBall:    "slow ball",
+	LifeRefill:  "life",
+	PhantomBall: "phantom ball",
+	MultiBall:   "multi ball",
+}
+
+// Strength and length of the effects, times are in seconds
+const (
+	powerUpWideLeds     = 2.0
+	powerUpWideTime     = 10.0
+	powerUpSlowFactor   = 0.6
+	powerUpLifeFraction = 0.25
+	powerUpPhantomTime  = 1.5
+	powerUpExtraBalls   = 2
+)
+
+// Kinds named in a comma separated list, every kind for an empty list. An error for an unknown name, with every kind
+func ParsePowerUpKinds(names string) (kinds []PowerUpKind, err error) {
+
+	if strings.TrimSpace(names) == "" {
+		return PowerUpKinds, nil
+	}
+
+	for _, name := range strings.Split(names, ",") {
+		kind := PowerUpKind(strings.ToLower(strings.TrimSpace(name)))
+		if _, ok := powerUpColors[kind]; !ok {
+			return PowerUpKinds, fmt.Errorf("Unknown power-up %q", name)
+		}
+		kinds = append(kinds, kind)
+	}
+	return
+}
+
+// Give the effect of a power-up collected by ball to the players of a side, returns the balls split off it
+func ApplyPowerUp(kind PowerUpKind, players []*Player, ball *Ball) (split []*Ball) {
+
+	switch kind {
+	case WidePaddle:
+		for _, player := range players {
+			player.Widen(powerUpWideLeds, powerUpWideTime)
+		}
+	case SlowBall:
+		ball.slowNext = powerUpSlowFactor
+	case LifeRefill:
+		for _, player := range players {
+			player.Refill(powerUpLifeFraction)
+		}
+	case PhantomBall:
+		ball.phantomTime = powerUpPhantomTime
+	case MultiBall:
+		split = ball.Split(powerUpExtraBalls)
+	}
+
+	return
+}
+
+// Announced name of the power-up
+func (kind PowerUpKind) Name() string {
+	return powerUpNames[kind]
+}
+
+// A power-up waiting on the field, collected by the side that last hit a ball passing through it
+type PowerUp struct {
+	kind PowerUpKind
+
+	// led and row it's on
+	position, y float64
+
+	// time on the field so far and until it disappears
+	time, lifeTime float64
+
+	collected bool
+	zindex    ZIndex
+}
+
+var _ Drawable2D = &PowerUp{}
+var _ Snapshotter = &PowerUp{}
+
+// Construct a PowerUp at position and row y that waits lifeTime seconds to be collected
+func NewPowerUp(kind PowerUpKind, position, y, lifeTime float64) *PowerUp {
+	return &PowerUp{
+		kind:     kind,
+		position: position,
+		y:        y,
+		lifeTime: lifeTime,
+		zindex:   50,
+	}
+}
+
+// Effect of the power-up
+func (this *PowerUp) Kind() PowerUpKind {
+	return this.kind
+}
+
+// True once the power-up was collected or ran out of time
+func (this *PowerUp) Done() bool {
+	return this.collected || this.time >= this.lifeTime
+}
+
+// Collect the power-up if ball went through it during its last update after a player hit it, returns the player that hit it or nil
+func (this *PowerUp) Collect(ball *Ball) *Player {
+
+	if this.Done() || ball.hitBy == nil || math.Abs(ball.y-this.y) >= 1 {
+		return nil
+	}
+	if this.position < math.Min(ball.previousPosition, ball.position)-0.5 || this.position > math.Max(ball.previousPosition, ball.position)+0.5 {
+		return nil
+	}
+
+	this.collected = true
+	return ball.hitBy
+}
+
+// Color of the power-up, pulsing and fading in and out, nothing once it's collected
+func (this *PowerUp) color(distance float64) (RGBA, bool) {
+
+	if distance >= 1 || this.Done() {
+		return RGBA{}, false
+	}
+
+	fade := math.Min(1.0, math.Min(this.time, this.lifeTime-this.time)*2.0)
+	pulse := 0.6 + 0.4*math.Sin(this.time*2.0*math.Pi*2.0)
+
+	color := powerUpColors[this.kind]
+	color.A = uint8(255.0 * fade * pulse * (1.0 - distance))
+	return color, true
+}
+
+// Returns the color at position blended on top of baseColor
+func (this *PowerUp) ColorAt(position float64, baseColor RGBA) RGBA {
+	if color, ok := this.color(math.Abs(position - this.position)); ok {
+		return color.BlendWith(baseColor)
+	}
+	return baseColor
+}
+
+// Returns the color at x, y blended on top of baseColor
+func (this *PowerUp) ColorAtXY(x, y float64, baseColor RGBA) RGBA {
+	if color, ok := this.color(math.Hypot(x-this.position, y-this.y)); ok {
+		return color.BlendWith(baseColor)
+	}
+	return baseColor
+}
+
+// ZIndex
+func (this *PowerUp) ZIndex() ZIndex {
+	return this.zindex
+}
+
+// Copy of the state of the power-up
+func (this *PowerUp) Snapshot() interface{} {
+	return *this
+}
+
+// Go back to a state from Snapshot
+func (this *PowerUp) Restore(snapshot interface{}) {
+	*this = snapshot.(PowerUp)
+}
+
+// Animate, the power-up leaves the field once it's done
+func (this *PowerUp) Animate(dt float64) bool {
+	this.time += dt
+	return !this.Done()
+}
@@ -0,0 +1,104 @@
+package draw
+
+import (
+	"testing"
+)
+
+func Test_ParsePowerUpKinds(t *testing.T) {
+	kinds, err := ParsePowerUpKinds(" ")
+	if err != nil || len(kinds) != len(PowerUpKinds) {
+		t.Fatal("Empty list should be every kind", kinds, err)
+	}
+
+	kinds, err = ParsePowerUpKinds(" Wide, SLOW,multiball")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Assert(len(kinds), 3, "Kinds", t)
+	if kinds[0] != WidePaddle || kinds[1] != SlowBall || kinds[2] != MultiBall {
+		t.Fatal("Kinds", kinds)
+	}
+
+	kinds, err = ParsePowerUpKinds("wide,laser")
+	if err == nil {
+		t.Fatal("Unknown power-up accepted")
+	}
+	Assert(len(kinds), len(PowerUpKinds), "Kinds with an unknown name", t)
+}
+
+// A power-up is collected by the player that last hit a ball going through it on its row, once
+func Test_PowerUp_Collect(t *testing.T) {
+	player := NewPlayer(true, 10, newTestField(1))
+	powerUp := NewPowerUp(SlowBall, 30, 0, 10)
+
+	if powerUp.Collect(&Ball{previousPosition: 28, position: 32}) != nil {
+		t.Fatal("Collected by a served ball")
+	}
+	if powerUp.Collect(&Ball{previousPosition: 20, position: 25, hitBy: player}) != nil {
+		t.Fatal("Collected by a ball short of it")
+	}
+	if powerUp.Collect(&Ball{previousPosition: 28, position: 32, y: 1, hitBy: player}) != nil {
+		t.Fatal("Collected by a ball on another row")
+	}
+
+	if powerUp.Collect(&Ball{previousPosition: 32, position: 29.6, hitBy: player}) != player {
+		t.Fatal("Not collected by the ball going through it")
+	}
+	Assert(boolToInt(powerUp.Done()), 1, "Done once collected", t)
+	if powerUp.Collect(&Ball{previousPosition: 28, position: 32, hitBy: player}) != nil {
+		t.Fatal("Collected twice")
+	}
+
+	expired := NewPowerUp(SlowBall, 30, 0, 1)
+	expired.Animate(1)
+	if expired.Collect(&Ball{previousPosition: 28, position: 32, hitBy: player}) != nil {
+		t.Fatal("Collected after its time")
+	}
+}
+
+// Each kind changes the players of the side or the ball that collected it
+func Test_ApplyPowerUp(t *testing.T) {
+	field := newTestField(1)
+	outer, inner := NewDoublesPlayers(true, 6, 10, field)
+	players := []*Player{outer, inner}
+	ball := newTestBall(field, 30, 20)
+
+	ApplyPowerUp(WidePaddle, players, ball)
+	for _, player := range players {
+		assertNear(player.paddleRight-player.paddleLeft, 1+powerUpWideLeds, "Wide paddle", t)
+	}
+	outer.Animate(powerUpWideTime)
+	assertNear(outer.paddleRight-outer.paddleLeft, 1, "Paddle after the power-up", t)
+
+	ApplyPowerUp(SlowBall, players, ball)
+	assertNear(ball.slowNext, powerUpSlowFactor, "Slow ball", t)
+
+	outer.DecreaseLife(5)
+	inner.DecreaseLife(1)
+	ApplyPowerUp(LifeRefill, players, ball)
+	assertNear(outer.Life(), 7.5, "Refilled life", t)
+	assertNear(inner.Life(), 10, "Life refilled up to full", t)
+
+	ApplyPowerUp(PhantomBall, players, ball)
+	assertNear(ball.phantomTime, powerUpPhantomTime, "Phantom ball", t)
+
+	Assert(len(ApplyPowerUp(WidePaddle, players, ball)), 0, "Balls split off by another kind", t)
+	Assert(len(ApplyPowerUp(MultiBall, players, ball)), powerUpExtraBalls, "Balls split off", t)
+}
+
+// Split balls start where the ball is, slower and faster in turn, every other one across the rows the other way
+func Test_Ball_Split(t *testing.T) {
+	ball := &Ball{position: 30, velocity: 20, velocityY: 4}
+
+	balls := ball.Split(3)
+	Assert(len(balls), 3, "Balls", t)
+	for index, expected := range []struct{ velocity, velocityY float64 }{{16, -4}, {24, 4}, {12, -4}} {
+		assertNear(balls[index].position, 30, "Position", t)
+		assertNear(balls[index].velocity, expected.velocity, "Velocity", t)
+		assertNear(balls[index].velocityY, expected.velocityY, "Velocity across the rows", t)
+	}
+
+	balls[0].position = 40
+	assertNear(ball.position, 30, "Position of the original after moving a split ball", t)
+	assertNear(ball.velocity, 20, "Velocity of the original", t)
+}
@@ -0,0 +1,108 @@
+package draw
+
+import (
+	"math"
+	. "pong"
+)
+
+// Shows the rounds won in a match as pips spreading out from the middle of each half
+type Scoreboard struct {
+
+	// first led of the right half
+	center float64
+
+	// rounds won by each player and the rounds needed to win
+	leftRounds, rightRounds, roundsToWin int
+
+	// total time counted so far
+	time float64
+
+	// length of the whole animation
+	totalTime float64
+}
+
+var _ Drawable = &Scoreboard{}
+var _ Snapshotter = &Scoreboard{}
+
+// leds between two pips
+const scoreboardPipSpacing = 2.0
+
+// Construct a Scoreboard shown for totalTime seconds
+func NewScoreboard(field *GameField, leftRounds, rightRounds, roundsToWin int, totalTime float64) *Scoreboard {
+	return &Scoreboard{
+		center:      float64(field.Width() / 2),
+		leftRounds:  leftRounds,
+		rightRounds: rightRounds,
+		roundsToWin: roundsToWin,
+		totalTime:   totalTime,
+	}
+}
+
+// Returns the color at position blended on top of baseColor
+func (this *Scoreboard) ColorAt(position float64, baseColor RGBA) RGBA {
+
+	// the pips fade in and out
+	fade := math.Min(1.0, math.Min(this.time, this.totalTime-this.time)*4.0)
+
+	// pips grow outwards from the middle, the left player's to the left
+	var pip, rounds int
+	var color RGBA
+	if position < this.center {
+		offset := this.center - 1 - position
+		if math.Mod(offset, scoreboardPipSpacing) != 0 {
+			return baseColor
+		}
+		pip, rounds, color = int(offset/scoreboardPipSpacing), this.leftRounds, RGBA{0, 0, 255, 255}
+	} else {
+		offset := position - this.center
+		if math.Mod(offset, scoreboardPipSpacing) != 0 {
+			return baseColor
+		}
+		pip, rounds, color = int(offset/scoreboardPipSpacing), this.rightRounds, RGBA{0, 255, 0, 255}
+	}
+
+	if pip >= this.roundsToWin {
+		return baseColor
+	}
+
+	// rounds still to win are shown dimmed
+	alpha := 255.0 * fade
+	if pip >= rounds {
+		alpha *= 0.15
+	}
+
+	color.A = uint8(alpha)
+	return color.BlendWith(baseColor)
+}
+
+// ZIndex
+func (this *Scoreboard) ZIndex() ZIndex {
+	return 0
+}
+
+// Copy of the state of the scoreboard
+func (this *Scoreboard) Snapshot() interface{} {
+	return *this
+}
+
+// Go back to a state from Snapshot
+func (this *Scoreboard) Restore(snapshot interface{}) {
+	*this = snapshot.(Scoreboard)
+}
+
+// Animate
+func (this *Scoreboard) Animate(dt float64) bool {
+
+	this.time += dt
+
+	if this.time >= this.totalTime {
+		this.time = this.totalTime
+	}
+
+	return true
+}
+
+// Amount of time remaining in the animation
+func (this *Scoreboard) TimeRemaining() float64 {
+	return this.totalTime - this.time
+}
@@ -0,0 +1,98 @@
+package draw
+
+import (
+	. "pong"
+)
+
+// Patterns shown while calibrating, in the order they are cycled through
+var testPatternNames = []string{
+	"red ramp",
+	"green ramp",
+	"blue ramp",
+	"white ramp",
+	"primaries",
+	"white",
+	"players",
+}
+
+// Represents a still test pattern used to tune gamma, color order and white balance by eye
+type TestPattern struct {
+
+	// length of field
+	scale float64
+
+	// index into testPatternNames
+	pattern int
+
+	zindex ZIndex
+}
+
+var _ Drawable = &TestPattern{}
+
+// Construct a TestPattern showing the first pattern
+func NewTestPattern(field *GameField, zindex ZIndex) *TestPattern {
+	return &TestPattern{
+		scale:  float64(field.Width()),
+		zindex: zindex,
+	}
+}
+
+// Show the next pattern, wraps around after the last one
+func (this *TestPattern) Next() {
+	this.pattern = (this.pattern + 1) % len(testPatternNames)
+}
+
+// Show the previous pattern, wraps around before the first one
+func (this *TestPattern) Previous() {
+	this.pattern = (this.pattern + len(testPatternNames) - 1) % len(testPatternNames)
+}
+
+// Name of the pattern being shown
+func (this *TestPattern) Name() string {
+	return testPatternNames[this.pattern]
+}
+
+// Returns the color of the pattern at position, baseColor is covered
+func (this *TestPattern) ColorAt(position float64, baseColor RGBA) RGBA {
+
+	// 0 to 1
+	fieldPercentage := position / this.scale
+	ramp := uint8(fieldPercentage*255.0 + 0.5)
+
+	switch testPatternNames[this.pattern] {
+	case "red ramp":
+		return RGBA{ramp, 0, 0, 255}
+	case "green ramp":
+		return RGBA{0, ramp, 0, 255}
+	case "blue ramp":
+		return RGBA{0, 0, ramp, 255}
+	case "white ramp":
+		return RGBA{ramp, ramp, ramp, 255}
+	case "primaries":
+		switch {
+		case fieldPercentage < 1.0/3.0:
+			return RGBA{255, 0, 0, 255}
+		case fieldPercentage < 2.0/3.0:
+			return RGBA{0, 255, 0, 255}
+		}
+		return RGBA{0, 0, 255, 255}
+	case "white":
+		return RGBA{255, 255, 255, 255}
+	}
+
+	// the paddle colors of the left and right players
+	if fieldPercentage < 0.5 {
+		return RGBA{0, 0, 255, 255}
+	}
+	return RGBA{0, 255, 0, 255}
+}
+
+// ZIndex
+func (this *TestPattern) ZIndex() ZIndex {
+	return this.zindex
+}
+
+// Animate, the patterns are still
+func (this *TestPattern) Animate(dt float64) bool {
+	return true
+}
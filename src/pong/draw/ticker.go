@@ -0,0 +1,206 @@
+package draw
+
+import (
+	"math"
+	. "pong"
+	"strings"
+)
+
+// A line scrolled by a Ticker, its text is shown on fields tall enough for the font and its bar of leds on the others
+type TickerEntry struct {
+	Text  string
+	Bar   int
+	Color RGBA
+}
+
+// Scrolls entries across the field from its right edge until the last one has left it, dimming whatever is below
+type Ticker struct {
+
+	// color of each column scrolled across the field, alpha 0 between entries
+	columns []RGBA
+
+	// lit pixels of each row of the font in every column, nil when bars are shown
+	rows [][]bool
+
+	// row of the field where the top of the text is
+	top int
+
+	width  float64
+	zindex ZIndex
+
+	// total time counted so far
+	time float64
+}
+
+var _ Drawable2D = &Ticker{}
+var _ Snapshotter = &Ticker{}
+
+// leds the ticker moves each second
+const tickerSpeed = 10.0
+
+// blank columns between two entries
+const tickerEntryGap = 4
+
+// alpha of the black drawn over everything below the ticker
+const tickerDimming = 200
+
+// Letters 3 pixels wide and tickerFontHeight high, lower case is shown as upper case and anything else as a space
+const tickerFontHeight = 5
+
+var tickerFont = map[rune][tickerFontHeight]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", ".##", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", ".#.", ".#.", ".#."},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'A': {".#.", "#.#", "###", "#.#", "#.#"},
+	'B': {"##.", "#.#", "##.", "#.#", "##."},
+	'C': {".##", "#..", "#..", "#..", ".##"},
+	'D': {"##.", "#.#", "#.#", "#.#", "##."},
+	'E': {"###", "#..", "##.", "#..", "###"},
+	'F': {"###", "#..", "##.", "#..", "#.."},
+	'G': {".##", "#..", "#.#", "#.#", ".##"},
+	'H': {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I': {"###", ".#.", ".#.", ".#.", "###"},
+	'J': {"..#", "..#", "..#", "#.#", ".#."},
+	'K': {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L': {"#..", "#..", "#..", "#..", "###"},
+	'M': {"#.#", "###", "###", "#.#", "#.#"},
+	'N': {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O': {".#.", "#.#", "#.#", "#.#", ".#."},
+	'P': {"##.", "#.#", "##.", "#..", "#.."},
+	'Q': {".#.", "#.#", "#.#", "##.", ".##"},
+	'R': {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S': {".##", "#..", ".#.", "..#", "##."},
+	'T': {"###", ".#.", ".#.", ".#.", ".#."},
+	'U': {"#.#", "#.#", "#.#", "#.#", "###"},
+	'V': {"#.#", "#.#", "#.#", "#.#", ".#."},
+	'W': {"#.#", "#.#", "###", "###", "#.#"},
+	'X': {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y': {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z': {"###", "..#", ".#.", "#..", "###"},
+	'.': {"...", "...", "...", "...", ".#."},
+	':': {"...", ".#.", "...", ".#.", "..."},
+	'-': {"...", "...", "###", "...", "..."},
+}
+
+// Construct a Ticker, bars are at most a quarter of the field long
+func NewTicker(field *GameField, entries []TickerEntry, zindex ZIndex) *Ticker {
+
+	ticker := &Ticker{
+		width:  float64(field.Width()),
+		zindex: zindex,
+	}
+
+	if field.Height() >= tickerFontHeight {
+		ticker.top = (field.Height() - tickerFontHeight) / 2
+		ticker.rows = make([][]bool, tickerFontHeight)
+		for _, entry := range entries {
+			for _, letter := range strings.ToUpper(entry.Text) {
+				ticker.addGlyph(tickerFont[letter], entry.Color)
+			}
+			ticker.addGap(tickerEntryGap)
+		}
+		return ticker
+	}
+
+	for _, entry := range entries {
+		bar := entry.Bar
+		if bar > field.Width()/4 {
+			bar = field.Width() / 4
+		}
+		for led := 0; led < bar; led++ {
+			ticker.columns = append(ticker.columns, entry.Color)
+		}
+		ticker.addGap(tickerEntryGap)
+	}
+	return ticker
+}
+
+// Add the columns of a letter and the blank one after it, a missing letter is a space
+func (this *Ticker) addGlyph(glyph [tickerFontHeight]string, color RGBA) {
+	for column := 0; column < 4; column++ {
+		for row := range this.rows {
+			this.rows[row] = append(this.rows[row], column < len(glyph[row]) && glyph[row][column] == '#')
+		}
+		this.columns = append(this.columns, color)
+	}
+}
+
+// Add blank columns
+func (this *Ticker) addGap(columns int) {
+	for column := 0; column < columns; column++ {
+		for row := range this.rows {
+			this.rows[row] = append(this.rows[row], false)
+		}
+		this.columns = append(this.columns, RGBA{})
+	}
+}
+
+// Seconds from the first column entering the field until the last one has left it
+func (this *Ticker) Duration() float64 {
+	return (this.width + float64(len(this.columns))) / tickerSpeed
+}
+
+// Column shown at x, false when there is none
+func (this *Ticker) column(x float64) (int, bool) {
+	column := int(math.Floor(x - this.width + this.time*tickerSpeed))
+	return column, 0 <= column && column < len(this.columns)
+}
+
+// Dims baseColor, fading in and out at the start and end
+func (this *Ticker) dim(baseColor RGBA) RGBA {
+	fade := math.Max(0, math.Min(1.0, math.Min(this.time, this.Duration()-this.time)))
+	return RGBA{0, 0, 0, uint8(tickerDimming * fade)}.BlendWith(baseColor)
+}
+
+// Returns the color at position blended on top of baseColor
+func (this *Ticker) ColorAt(position float64, baseColor RGBA) RGBA {
+
+	color := this.dim(baseColor)
+	if column, ok := this.column(position); ok && this.rows == nil {
+		return this.columns[column].BlendWith(color)
+	}
+	return color
+}
+
+// Returns the color at x, y blended on top of baseColor
+func (this *Ticker) ColorAtXY(x, y float64, baseColor RGBA) RGBA {
+
+	if this.rows == nil {
+		return this.ColorAt(x, baseColor)
+	}
+
+	color := this.dim(baseColor)
+	row := int(y) - this.top
+	if column, ok := this.column(x); ok && 0 <= row && row < tickerFontHeight && this.rows[row][column] {
+		return this.columns[column].BlendWith(color)
+	}
+	return color
+}
+
+// ZIndex
+func (this *Ticker) ZIndex() ZIndex {
+	return this.zindex
+}
+
+// Copy of the state of the ticker
+func (this *Ticker) Snapshot() interface{} {
+	return *this
+}
+
+// Go back to a state from Snapshot
+func (this *Ticker) Restore(snapshot interface{}) {
+	*this = snapshot.(Ticker)
+}
+
+// Animate, the ticker is done once its last column has left the field
+func (this *Ticker) Animate(dt float64) bool {
+	this.time += dt
+	return this.time < this.Duration()
+}
@@ -23,6 +23,21 @@ type Drawable interface {
 	Animate(dt float64) (keepAlive bool)
 }
 
+// Methods required to draw something with height on a 2D field, Drawables without them are stretched across every row
+type Drawable2D interface {
+	Drawable
+
+	// Computes the color at x, y with the given baseColor, y is the row with 0 at the top
+	ColorAtXY(x, y float64, baseColor RGBA) RGBA
+}
+
+// Drawables that move smoothly between fixed updates
+type Interpolated interface {
+
+	// Draw alpha of the way from the state before the last Animate to the current one, alpha is from 0 to 1
+	Interpolate(alpha float64)
+}
+
 // Helper function to blend two colors together
 func (foreground RGBA) BlendWith(background RGBA) (color RGBA) {
 
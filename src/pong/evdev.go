@@ -0,0 +1,102 @@
+// +build !windows
+
+package pong
+
+import (
+	"io"
+	"log"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Constants of the evdev interface from linux/input-event-codes.h
+const (
+	EV_KEY = 0x01
+
+	BTN_SOUTH = 0x130
+	BTN_EAST  = 0x131
+	BTN_TL    = 0x136
+	BTN_TR    = 0x137
+	BTN_TL2   = 0x138
+	BTN_TR2   = 0x139
+)
+
+// struct input_event
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// Buttons of a gamepad or arcade encoder read from /dev/input/event*
+type EvdevInput struct {
+	*buttonEvents
+
+	// key code of each player's button
+	codes map[uint16]ButtonId
+}
+
+// Read the buttons from the event device of input, the codes default to the shoulder buttons and the triggers for the inner players
+func NewEvdevInput(input InputSettings, settings SettingsData) *EvdevInput {
+
+	file, err := os.Open(input.Device)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return newEvdevInput(file, input, settings)
+}
+
+// Construct an EvdevInput reading events from reader with the codes of input
+func newEvdevInput(reader io.Reader, input InputSettings, settings SettingsData) *EvdevInput {
+
+	codes := [ButtonCount]int{input.LeftCode, input.RightCode, input.LeftInnerCode, input.RightInnerCode}
+	for button, defaultCode := range [ButtonCount]int{BTN_TL, BTN_TR, BTN_TL2, BTN_TR2} {
+		if codes[button] == 0 {
+			codes[button] = defaultCode
+		}
+	}
+
+	evdev := &EvdevInput{
+		buttonEvents: newButtonEvents(settings),
+		codes:        make(map[uint16]ButtonId),
+	}
+	for button, code := range codes {
+		evdev.codes[uint16(code)] = ButtonId(button)
+	}
+
+	go evdev.run(reader)
+
+	return evdev
+}
+
+// Turn key events into button events
+func (this *EvdevInput) run(reader io.Reader) {
+
+	events := make([]inputEvent, 16)
+	eventSize := int(unsafe.Sizeof(inputEvent{}))
+	data := (*[1 << 16]byte)(unsafe.Pointer(&events[0]))[: len(events)*eventSize : len(events)*eventSize]
+
+	for {
+		count, err := reader.Read(data)
+		if err != nil {
+			log.Print("Evdev input stopped ", err)
+			return
+		}
+
+		for _, event := range events[:count/eventSize] {
+
+			// value is 1 for a press, 0 for a release and 2 for key repeats
+			if event.Type != EV_KEY || event.Value == 2 {
+				continue
+			}
+			if button, ok := this.codes[event.Code]; ok {
+				sec, nsec := event.Time.Unix()
+				this.update(button, event.Value == 1, time.Unix(sec, nsec))
+			}
+		}
+	}
+}
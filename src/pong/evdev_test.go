@@ -0,0 +1,34 @@
+// +build !windows
+
+package pong
+
+import (
+	"io"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+func Test_EvdevInput_Buttons(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	input := newEvdevInput(reader, InputSettings{RightCode: BTN_SOUTH}, SettingsData{ButtonDebounceMs: -1})
+
+	events := []inputEvent{
+		{Time: syscall.Timeval{Sec: 100}, Type: EV_KEY, Code: BTN_EAST, Value: 1}, // not mapped
+		{Time: syscall.Timeval{Sec: 101}, Type: EV_KEY, Code: BTN_SOUTH, Value: 1},
+		{Time: syscall.Timeval{Sec: 102}, Type: EV_KEY, Code: BTN_SOUTH, Value: 2}, // repeat
+		{Time: syscall.Timeval{Sec: 103}, Type: EV_KEY, Code: BTN_TL, Value: 1},
+	}
+	size := len(events) * int(unsafe.Sizeof(inputEvent{}))
+	writer.Write((*[1 << 16]byte)(unsafe.Pointer(&events[0]))[:size])
+
+	press := nextPress(input, t)
+	Assert(int(press.Button), int(RightButtonId), "right button", t)
+	Assert(int(press.Time.Unix()), 101, "event time", t)
+
+	press = nextPress(input, t)
+	Assert(int(press.Button), int(LeftButtonId), "left defaults to the left shoulder", t)
+	Assert(int(press.Event), int(ButtonPush), "pushed", t)
+}
@@ -0,0 +1,18 @@
+// +build windows
+
+package pong
+
+import (
+	"log"
+)
+
+// Buttons of a gamepad read from /dev/input/event*, only available on linux
+type EvdevInput struct {
+	*buttonEvents
+}
+
+// Evdev devices don't exist on windows
+func NewEvdevInput(input InputSettings, settings SettingsData) *EvdevInput {
+	log.Fatal("Evdev input isn't available on windows")
+	return nil
+}
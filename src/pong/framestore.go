@@ -0,0 +1,101 @@
+package pong
+
+import (
+	"sync"
+	"time"
+)
+
+// A single rendered frame, never modified after it is stored
+type Frame struct {
+
+	// increases by one for every stored frame, the first frame is 1
+	Sequence uint64
+
+	// when the frame was stored
+	Time time.Time
+
+	// colors of each led
+	Colors []RGBA
+}
+
+// Holds the most recent frame so that it can be shared between goroutines
+type FrameStore struct {
+	lock sync.Mutex
+
+	// most recently stored frame, nil until the first Store
+	latest *Frame
+
+	// closed and replaced every time a frame is stored to wake up waiters
+	updated chan struct{}
+}
+
+var _ Display = &FrameStore{}
+
+// Construct an empty FrameStore
+func NewFrameStore() *FrameStore {
+	return &FrameStore{
+		updated: make(chan struct{}),
+	}
+}
+
+// Copy colors into a new frame and publish it, returns the sequence number of the frame
+func (this *FrameStore) Store(colors []RGBA) uint64 {
+
+	// copy outside of the lock, the caller is free to reuse colors once this returns
+	frame := &Frame{
+		Time:   time.Now(),
+		Colors: make([]RGBA, len(colors)),
+	}
+	copy(frame.Colors, colors)
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if this.latest != nil {
+		frame.Sequence = this.latest.Sequence + 1
+	} else {
+		frame.Sequence = 1
+	}
+	this.latest = frame
+
+	close(this.updated)
+	this.updated = make(chan struct{})
+
+	return frame.Sequence
+}
+
+// Store the frame, allows a FrameStore to be used as a Display
+func (this *FrameStore) Render(colors []RGBA) {
+	this.Store(colors)
+}
+
+// Most recent frame or nil if nothing has been stored yet
+func (this *FrameStore) Latest() *Frame {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	return this.latest
+}
+
+// Wait for a frame newer than sequence, returns nil if none arrived before timeout
+func (this *FrameStore) WaitNewer(sequence uint64, timeout time.Duration) *Frame {
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		this.lock.Lock()
+		latest, updated := this.latest, this.updated
+		this.lock.Unlock()
+
+		if latest != nil && latest.Sequence > sequence {
+			return latest
+		}
+
+		select {
+		case <-updated:
+		case <-timer.C:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package pong
+
+import (
+	"testing"
+	"time"
+)
+
+// Stored frames must be copies, so reusing the render buffer doesn't change them
+func Test_FrameStore_CopyOnStore(t *testing.T) {
+	store := NewFrameStore()
+	if store.Latest() != nil {
+		t.Fatal("Expected no frame before the first Store")
+	}
+
+	buffer := []RGBA{{1, 1, 1, 255}, {2, 2, 2, 255}}
+	Assert(int(store.Store(buffer)), 1, "First sequence", t)
+
+	buffer[0] = RGBA{9, 9, 9, 255}
+	Assert(int(store.Store(buffer)), 2, "Second sequence", t)
+
+	latest := store.Latest()
+	Assert(int(latest.Sequence), 2, "Latest sequence", t)
+	Assert(int(latest.Colors[0].R), 9, "Latest color", t)
+
+	buffer[0] = RGBA{5, 5, 5, 255}
+	Assert(int(latest.Colors[0].R), 9, "Stored frame changed after buffer reuse", t)
+}
+
+// WaitNewer returns immediately when a newer frame exists, blocks until one is stored, and times out otherwise
+func Test_FrameStore_WaitNewer(t *testing.T) {
+	store := NewFrameStore()
+	store.Store([]RGBA{{1, 1, 1, 255}})
+
+	if frame := store.WaitNewer(0, time.Second); frame == nil || frame.Sequence != 1 {
+		t.Fatal("Expected the existing frame")
+	}
+
+	if frame := store.WaitNewer(1, 10*time.Millisecond); frame != nil {
+		t.Fatal("Expected timeout, got sequence", frame.Sequence)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		store.Store([]RGBA{{2, 2, 2, 255}})
+	}()
+
+	frame := store.WaitNewer(1, 5*time.Second)
+	if frame == nil {
+		t.Fatal("Expected a frame to arrive")
+	}
+	Assert(int(frame.Sequence), 2, "Waited sequence", t)
+	Assert(int(frame.Colors[0].R), 2, "Waited color", t)
+}
@@ -2,6 +2,7 @@ package pong
 
 import (
 	"container/list"
+	"time"
 )
 
 // Defines all of the information
@@ -10,23 +11,46 @@ type GameField struct {
 	// Size of the field, from 0 to width exclusive
 	width int
 
+	// Number of rows, 1 unless the field is shown on a matrix
+	height int
+
 	// All of the drawable items, stored in increasing ZIndex order
 	drawables *list.List
 
 	// Buffer used to render the field
 	renderBuffer []RGBA
+
+	// random numbers used by the drawables, the same seed plays the same game
+	random *Random
 }
 
 // Initialized a new field
 func NewGameField(width int) *GameField {
+	return NewGameField2D(width, 1)
+}
+
+// Initialize a new field with height rows
+func NewGameField2D(width, height int) *GameField {
 
 	return &GameField{
 		width:        width,
+		height:       height,
 		drawables:    list.New(),
-		renderBuffer: make([]RGBA, width),
+		renderBuffer: make([]RGBA, width*height),
+		random:       NewRandom(uint64(time.Now().UnixNano())),
 	}
 }
 
+// Random numbers for the drawables of the field
+func (field *GameField) Random() *Random {
+	return field.random
+}
+
+// Use random for the drawables added after this, sharing one between fields continues its sequence across them
+func (field *GameField) SetRandom(random *Random) {
+	field.random = random
+}
+
 // Adds a drawable to the field
 func (field *GameField) Add(addDrawable Drawable) {
 
@@ -66,6 +90,24 @@ func (field *GameField) ColorAt(position float64) RGBA {
 	return color
 }
 
+// Determines the color at the given x, y, using ColorAt for Drawables that don't have height
+func (field *GameField) ColorAtXY(x, y float64) RGBA {
+
+	color := RGBA{0, 0, 0, 255}
+
+	for curElement := field.drawables.Front(); curElement != nil; curElement = curElement.Next() {
+
+		switch drawable := curElement.Value.(type) {
+		case Drawable2D:
+			color = drawable.ColorAtXY(x, y, color)
+		case Drawable:
+			color = drawable.ColorAt(x, color)
+		}
+	}
+
+	return color
+}
+
 // Animate all Drawables
 func (field *GameField) Animate(dt float64) {
 
@@ -83,11 +125,29 @@ func (field *GameField) Animate(dt float64) {
 	}
 }
 
-// Render each integer position and pass that to the Display
+// Show Interpolated drawables alpha of the way from their previous to their current update
+func (field *GameField) Interpolate(alpha float64) {
+
+	for curElement := field.drawables.Front(); curElement != nil; curElement = curElement.Next() {
+		if drawable, ok := curElement.Value.(Interpolated); ok {
+			drawable.Interpolate(alpha)
+		}
+	}
+}
+
+// Render each integer position and pass that to the Display, rows are rendered one after another
 func (field *GameField) RenderTo(display Display) {
 
-	for ledIndex := 0; ledIndex < field.width; ledIndex++ {
-		field.renderBuffer[ledIndex] = field.ColorAt(float64(ledIndex))
+	if field.height == 1 {
+		for ledIndex := 0; ledIndex < field.width; ledIndex++ {
+			field.renderBuffer[ledIndex] = field.ColorAt(float64(ledIndex))
+		}
+	} else {
+		for y := 0; y < field.height; y++ {
+			for x := 0; x < field.width; x++ {
+				field.renderBuffer[y*field.width+x] = field.ColorAtXY(float64(x), float64(y))
+			}
+		}
 	}
 	display.Render(field.renderBuffer)
 }
@@ -122,3 +182,8 @@ func (field *GameField) DrawableLen() int {
 func (field *GameField) Width() int {
 	return field.width
 }
+
+// Number of rows in the field
+func (field *GameField) Height() int {
+	return field.height
+}
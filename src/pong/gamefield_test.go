@@ -58,6 +58,103 @@ func Test_GameField_AddAnimate(t *testing.T) {
 	Assert(field.DrawableLen(), 0, "Field should be empty", t)
 }
 
+func (countdown *CountdownDrawable) Snapshot() interface{} {
+	return *countdown
+}
+
+func (countdown *CountdownDrawable) Restore(snapshot interface{}) {
+	*countdown = snapshot.(CountdownDrawable)
+}
+
+// Restoring a snapshot puts back drawables that were removed since, with their state and the random numbers
+func Test_GameField_SnapshotRestore(t *testing.T) {
+	field := NewGameField(10)
+	field.SetRandom(NewRandom(5))
+	short, long := &CountdownDrawable{maxLife: 1}, &CountdownDrawable{maxLife: 3}
+	field.Add(short)
+	field.Add(long)
+	field.Add(&solidDrawable{})
+
+	field.Animate(0.5)
+	snapshot := field.Snapshot()
+	next := field.Random().Uint64()
+
+	field.Animate(1.0)
+	field.Random().Uint64()
+	Assert(field.DrawableLen(), 2, "short removed", t)
+
+	for restore := 0; restore < 2; restore++ {
+		field.Restore(snapshot)
+		Assert(field.DrawableLen(), 3, "short put back", t)
+		Assert(int(short.curLife*10), 5, "short life", t)
+		Assert(int(long.curLife*10), 5, "long life", t)
+		if field.Random().Uint64() != next {
+			t.Fatal("random numbers weren't restored")
+		}
+		field.Animate(1.0)
+	}
+}
+
+// Drawable with height that only colors the given row
+type rowDrawable struct {
+	row float64
+}
+
+func (row *rowDrawable) ColorAt(position float64, baseColor RGBA) RGBA {
+	return baseColor
+}
+
+func (row *rowDrawable) ColorAtXY(x, y float64, baseColor RGBA) RGBA {
+	if y == row.row {
+		return RGBA{0, 255, 0, 255}
+	}
+	return baseColor
+}
+
+func (row *rowDrawable) ZIndex() ZIndex {
+	return 2
+}
+
+func (row *rowDrawable) Animate(dt float64) (keepAlive bool) {
+	return true
+}
+
+// Drawable that colors every position red
+type solidDrawable struct {
+}
+
+func (solid *solidDrawable) ColorAt(position float64, baseColor RGBA) RGBA {
+	return RGBA{255, 0, 0, 255}
+}
+
+func (solid *solidDrawable) ZIndex() ZIndex {
+	return 1
+}
+
+func (solid *solidDrawable) Animate(dt float64) (keepAlive bool) {
+	return true
+}
+
+// On a 2d field Drawables without height fill every row, Drawable2D can draw single rows
+func Test_GameField_Render2D(t *testing.T) {
+	field := NewGameField2D(4, 3)
+	field.Add(&solidDrawable{})
+	field.Add(&rowDrawable{row: 1})
+
+	display := &collectingDisplay{}
+	field.RenderTo(display)
+
+	frame := display.frames[0]
+	Assert(len(frame), 12, "Frame size", t)
+	for index, color := range frame {
+		if index/4 == 1 {
+			Assert(int(color.G), 255, "Row drawn by Drawable2D", t)
+		} else {
+			Assert(int(color.R), 255, "Row drawn by Drawable", t)
+		}
+	}
+}
+
 // Helper assert method
 func Assert(actual, expected int, message string, t *testing.T) {
 	if actual != expected {
@@ -3,123 +3,150 @@
 package pong
 
 import (
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 // Type representing a bus connection
 type GpioReader struct {
+	*buttonEvents
+}
+
+// Buttons read through sysfs value files
+type sysfsButtons struct {
 	leftButtonFile  *os.File
-	leftPrevious    bool
 	rightButtonFile *os.File
-	rightPrevious   bool
 	data            []byte
+
+	*buttonEvents
 }
 
-// exports already run: gpio export 27 in and gpio export 22 in
+// how often the value files are read when edge events aren't available
+const gpioPollInterval = 2 * time.Millisecond
+
+// Read the buttons with the backend named in settings
 func NewGpioReader(settings SettingsData) *GpioReader {
 
 	reader := &GpioReader{
-		data: make([]byte, 32),
+		buttonEvents: newButtonEvents(settings),
 	}
 
-	var err error
-	_, err = os.Stat(settings.LeftButtonPath)
-	if err != nil && os.IsNotExist(err) {
-		cmd := exec.Command("/usr/local/bin/gpio", "export", settings.LeftButtonGpioPort, "in")
-		err = cmd.Run()
+	switch strings.ToLower(settings.GpioBackend) {
+	case "", GpioSysfs:
+		newSysfsButtons(settings, reader.buttonEvents)
+	case GpioChardev, "gpiochip":
+		path := settings.GpioChip
+		if path == "" {
+			path = defaultGpioChip
+		}
+		chip, err := openGpioChip(path)
 		if err != nil {
 			log.Fatal(err)
 		}
+		if err := chip.watchButtons(settings, reader.buttonEvents); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatal("Unknown GPIO backend ", settings.GpioBackend)
 	}
-	reader.leftButtonFile, err = os.Open(settings.LeftButtonPath)
-	if err != nil {
-		log.Fatal(err)
+
+	return reader
+}
+
+// exports already run: gpio export 27 in and gpio export 22 in
+func newSysfsButtons(settings SettingsData, events *buttonEvents) *sysfsButtons {
+
+	reader := &sysfsButtons{
+		data:         make([]byte, 32),
+		buttonEvents: events,
 	}
 
-	_, err = os.Stat(settings.RightButtonPath)
+	reader.leftButtonFile = openGpioValue(settings.LeftButtonPath, settings.LeftButtonGpioPort)
+	reader.rightButtonFile = openGpioValue(settings.RightButtonPath, settings.RightButtonGpioPort)
+
+	reader.down[LeftButtonId] = reader.readValue(reader.leftButtonFile)
+	reader.down[RightButtonId] = reader.readValue(reader.rightButtonFile)
+
+	go reader.run()
+
+	return reader
+}
+
+// Export the port when needed, ask for interrupts on both edges and open the value file
+func openGpioValue(path, port string) *os.File {
+
+	_, err := os.Stat(path)
 	if err != nil && os.IsNotExist(err) {
-		cmd := exec.Command("/usr/local/bin/gpio", "export", settings.RightButtonGpioPort, "in")
+		cmd := exec.Command("/usr/local/bin/gpio", "export", port, "in")
 		err = cmd.Run()
 		if err != nil {
 			log.Fatal(err)
 		}
 	}
-	reader.rightButtonFile, err = os.Open(settings.RightButtonPath)
-	if err != nil {
-		log.Fatal(err)
-	}
 
-	return reader
-}
-
-type ButtonEvent int
-
-const (
-	NoEvent ButtonEvent = iota
-	ButtonPush
-	ButtonRelease
-)
+	edgePath := filepath.Join(filepath.Dir(path), "edge")
+	if err := ioutil.WriteFile(edgePath, []byte("both"), 0644); err != nil {
+		log.Print("Can't enable edge events for ", path, ", ", err)
+	}
 
-// get state of the right button
-func (this *GpioReader) LeftButton() bool {
-	count, err := this.leftButtonFile.Read(this.data)
+	file, err := os.Open(path)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if count != 2 {
-		log.Fatal("Expected 2 bytes for left button read and got", count)
-	}
+	return file
+}
+
+// Read a value file, true while the button is held down
+func (this *sysfsButtons) readValue(file *os.File) bool {
 
 	// seek back to beginning of file
-	_, err = this.leftButtonFile.Seek(0, 0)
+	_, err := file.Seek(0, 0)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	buttonDown := this.data[0] == 48 // ascii '0'
-
-	return buttonDown
-
-	// if buttonDown != this.leftPrevious {
-	// 	this.leftPrevious = buttonDown
-
-	// 	if buttonDown {			
-	// 		return ButtonPush
-	// 	} else {
-	// 		return ButtonRelease
-	// 	}
-	// }
-}
-
-// Get state of the right button
-func (this *GpioReader) RightButton() bool {
-	count, err := this.rightButtonFile.Read(this.data)
+	count, err := file.Read(this.data)
 	if err != nil {
 		log.Fatal(err)
 	}
 	if count != 2 {
-		log.Fatal("Expected 2 bytes for right button read and got", count)
+		log.Fatal("Expected 2 bytes for button read and got", count)
 	}
 
-	// seek back to beginning of file
-	_, err = this.rightButtonFile.Seek(0, 0)
+	return this.data[0] == 48 // ascii '0'
+}
+
+// Wait for edges on the value files and turn them into events, polls the files when edges aren't available
+func (this *sysfsButtons) run() {
+
+	waiter, err := newEdgeWaiter(this.leftButtonFile, this.rightButtonFile)
 	if err != nil {
-		log.Fatal(err)
+		log.Print("Polling buttons every ", gpioPollInterval, ", edge events unavailable: ", err)
 	}
 
-	buttonDown := this.data[0] == 48 // ascii '0'
-
-	return buttonDown
+	settled := true
+	for {
+		var timeout time.Duration = -1
+		if !settled {
+			timeout = this.debounce // read again once the button has had time to settle
+		}
 
-	// if buttonDown != this.rightPrevious {
-	// 	this.rightPrevious = buttonDown
+		if waiter != nil {
+			if err := waiter.wait(timeout); err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			time.Sleep(gpioPollInterval)
+		}
 
-	// 	if buttonDown {
-	// 		return ButtonPush
-	// 	} else {
-	// 		return ButtonRelease
-	// 	}
-	// }
+		now := time.Now()
+		leftSettled := this.update(LeftButtonId, this.readValue(this.leftButtonFile), now)
+		rightSettled := this.update(RightButtonId, this.readValue(this.rightButtonFile), now)
+		settled = leftSettled && rightSettled
+	}
 }
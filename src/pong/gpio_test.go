@@ -0,0 +1,56 @@
+// +build !windows
+
+package pong
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_GpioReader_Events(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// regular files can't deliver edges, so this exercises the polling fallback
+	settings := SettingsData{
+		LeftButtonPath:  filepath.Join(dir, "gpio22", "value"),
+		RightButtonPath: filepath.Join(dir, "gpio27", "value"),
+	}
+	for _, path := range []string{settings.LeftButtonPath, settings.RightButtonPath} {
+		os.Mkdir(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte("1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reader := NewGpioReader(settings)
+	Assert(boolToInt(reader.LeftButton()), 0, "left starts up", t)
+
+	before := time.Now()
+	// overwrite in place, truncating would let the reader see an empty file
+	file, err := os.OpenFile(settings.LeftButtonPath, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteAt([]byte("0"), 0)
+	file.Close()
+
+	select {
+	case press := <-reader.Events():
+		Assert(int(press.Button), int(LeftButtonId), "button", t)
+		Assert(int(press.Event), int(ButtonPush), "event", t)
+		if press.Time.Before(before) {
+			t.Fatal("press timestamped before it happened")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+	Assert(boolToInt(reader.LeftButton()), 1, "left down", t)
+	Assert(boolToInt(reader.RightButton()), 0, "right still up", t)
+}
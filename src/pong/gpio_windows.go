@@ -6,24 +6,12 @@ import ()
 
 // Type representing a bus connection
 type GpioReader struct {
+	*buttonEvents
 }
 
+// There are no GPIO buttons on windows, they are never pushed
 func NewGpioReader(settings SettingsData) *GpioReader {
-	return &GpioReader{}
-}
-
-type ButtonEvent int
-
-const (
-	NoEvent ButtonEvent = iota
-	ButtonPush
-	ButtonRelease
-)
-
-func (this *GpioReader) LeftButton() bool {
-	return false
-}
-
-func (this *GpioReader) RightButton() bool {
-	return false
+	return &GpioReader{
+		buttonEvents: newButtonEvents(settings),
+	}
 }
@@ -0,0 +1,332 @@
+// +build !windows
+
+package pong
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Constants of the gpiochip v2 uAPI from linux/gpio.h
+const (
+	GPIO_GET_CHIPINFO_IOCTL       = 0x8044B401 // _IOR(0xB4, 0x01, struct gpiochip_info)
+	GPIO_V2_GET_LINEINFO_IOCTL    = 0xC100B405 // _IOWR(0xB4, 0x05, struct gpio_v2_line_info)
+	GPIO_V2_GET_LINE_IOCTL        = 0xC250B407 // _IOWR(0xB4, 0x07, struct gpio_v2_line_request)
+	GPIO_V2_LINE_GET_VALUES_IOCTL = 0xC010B40E // _IOWR(0xB4, 0x0E, struct gpio_v2_line_values)
+
+	GPIO_V2_LINE_FLAG_ACTIVE_LOW           = 1 << 1
+	GPIO_V2_LINE_FLAG_INPUT                = 1 << 2
+	GPIO_V2_LINE_FLAG_EDGE_RISING          = 1 << 4
+	GPIO_V2_LINE_FLAG_EDGE_FALLING         = 1 << 5
+	GPIO_V2_LINE_FLAG_BIAS_PULL_UP         = 1 << 8
+	GPIO_V2_LINE_FLAG_BIAS_PULL_DOWN       = 1 << 9
+	GPIO_V2_LINE_FLAG_BIAS_DISABLED        = 1 << 10
+	GPIO_V2_LINE_FLAG_EVENT_CLOCK_REALTIME = 1 << 11
+
+	GPIO_V2_LINE_ATTR_ID_DEBOUNCE = 3
+
+	GPIO_V2_LINE_EVENT_RISING_EDGE  = 1
+	GPIO_V2_LINE_EVENT_FALLING_EDGE = 2
+)
+
+// gpio chip used when GpioChip isn't set
+const defaultGpioChip = "/dev/gpiochip0"
+
+// struct gpiochip_info
+type gpiochipInfo struct {
+	Name  [32]byte
+	Label [32]byte
+	Lines uint32
+}
+
+// struct gpio_v2_line_attribute, Value holds flags, output values or the debounce period in microseconds
+type gpioV2LineAttribute struct {
+	Id      uint32
+	Padding uint32
+	Value   uint64
+}
+
+// struct gpio_v2_line_config_attribute
+type gpioV2LineConfigAttribute struct {
+	Attr gpioV2LineAttribute
+	Mask uint64
+}
+
+// struct gpio_v2_line_config
+type gpioV2LineConfig struct {
+	Flags    uint64
+	NumAttrs uint32
+	Padding  [5]uint32
+	Attrs    [10]gpioV2LineConfigAttribute
+}
+
+// struct gpio_v2_line_request
+type gpioV2LineRequest struct {
+	Offsets         [64]uint32
+	Consumer        [32]byte
+	Config          gpioV2LineConfig
+	NumLines        uint32
+	EventBufferSize uint32
+	Padding         [5]uint32
+	Fd              int32
+}
+
+// struct gpio_v2_line_info
+type gpioV2LineInfo struct {
+	Name     [32]byte
+	Consumer [32]byte
+	Offset   uint32
+	NumAttrs uint32
+	Flags    uint64
+	Attrs    [10]gpioV2LineAttribute
+	Padding  [4]uint32
+}
+
+// struct gpio_v2_line_values
+type gpioV2LineValues struct {
+	Bits uint64
+	Mask uint64
+}
+
+// struct gpio_v2_line_event
+type gpioV2LineEvent struct {
+	TimestampNs uint64
+	Id          uint32
+	Offset      uint32
+	Seqno       uint32
+	LineSeqno   uint32
+	Padding     [6]uint32
+}
+
+// A /dev/gpiochipN character device
+type gpioChip struct {
+	fd uintptr
+
+	// system calls, replaced in tests by a fake kernel
+	ioctl func(fd, request uintptr, arg unsafe.Pointer) error
+	read  func(fd uintptr, data []byte) (int, error)
+}
+
+// Lines requested from a gpioChip, reads their values and edge events
+type gpioLineRequest struct {
+	chip *gpioChip
+	fd   uintptr
+
+	// requested lines, in the order they were requested
+	offsets []uint32
+}
+
+// Open the chip at path
+func openGpioChip(path string) (*gpioChip, error) {
+
+	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gpioChip{
+		fd:    uintptr(fd),
+		ioctl: gpioIoctl,
+		read:  gpioRead,
+	}, nil
+}
+
+// Issue an ioctl on fd
+func gpioIoctl(fd, request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Blocking read from fd
+func gpioRead(fd uintptr, data []byte) (int, error) {
+	for {
+		count, err := syscall.Read(int(fd), data)
+		if err != syscall.EINTR {
+			return count, err
+		}
+	}
+}
+
+// Convert a NUL terminated C string
+func cString(data []byte) string {
+	if end := strings.IndexByte(string(data), 0); end >= 0 {
+		return string(data[:end])
+	}
+	return string(data)
+}
+
+// Find a line by its offset or, when line isn't a number, its name
+func (this *gpioChip) findLine(line string) (uint32, error) {
+
+	var info gpiochipInfo
+	if err := this.ioctl(this.fd, GPIO_GET_CHIPINFO_IOCTL, unsafe.Pointer(&info)); err != nil {
+		return 0, err
+	}
+
+	if offset, err := strconv.ParseUint(line, 10, 32); err == nil {
+		if uint32(offset) >= info.Lines {
+			return 0, fmt.Errorf("gpio line %d is past the %d lines of %s", offset, info.Lines, cString(info.Name[:]))
+		}
+		return uint32(offset), nil
+	}
+
+	for offset := uint32(0); offset < info.Lines; offset++ {
+		lineInfo := gpioV2LineInfo{Offset: offset}
+		if err := this.ioctl(this.fd, GPIO_V2_GET_LINEINFO_IOCTL, unsafe.Pointer(&lineInfo)); err != nil {
+			return 0, err
+		}
+		if cString(lineInfo.Name[:]) == line {
+			return offset, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no gpio line named %s on %s", line, cString(info.Name[:]))
+}
+
+// Request lines as inputs with flags, debounced by the kernel when debounce isn't 0
+func (this *gpioChip) requestLines(offsets []uint32, flags uint64, debounce time.Duration, consumer string) (*gpioLineRequest, error) {
+
+	request := gpioV2LineRequest{
+		NumLines: uint32(len(offsets)),
+	}
+	copy(request.Offsets[:], offsets)
+	copy(request.Consumer[:len(request.Consumer)-1], consumer)
+
+	request.Config.Flags = flags
+	if debounce > 0 {
+		request.Config.NumAttrs = 1
+		request.Config.Attrs[0] = gpioV2LineConfigAttribute{
+			Attr: gpioV2LineAttribute{Id: GPIO_V2_LINE_ATTR_ID_DEBOUNCE, Value: uint64(debounce / time.Microsecond)},
+			Mask: 1<<uint(len(offsets)) - 1,
+		}
+	}
+
+	if err := this.ioctl(this.fd, GPIO_V2_GET_LINE_IOCTL, unsafe.Pointer(&request)); err != nil {
+		return nil, err
+	}
+
+	return &gpioLineRequest{
+		chip:    this,
+		fd:      uintptr(request.Fd),
+		offsets: offsets,
+	}, nil
+}
+
+// Values of the requested lines, bit n is the line at offsets[n]
+func (this *gpioLineRequest) values() (uint64, error) {
+
+	values := gpioV2LineValues{Mask: 1<<uint(len(this.offsets)) - 1}
+	if err := this.chip.ioctl(this.fd, GPIO_V2_LINE_GET_VALUES_IOCTL, unsafe.Pointer(&values)); err != nil {
+		return 0, err
+	}
+	return values.Bits, nil
+}
+
+// Block until edge events arrive and read them into events
+func (this *gpioLineRequest) readEvents(events []gpioV2LineEvent) (int, error) {
+
+	eventSize := int(unsafe.Sizeof(gpioV2LineEvent{}))
+	data := (*[1 << 16]byte)(unsafe.Pointer(&events[0]))[: len(events)*eventSize : len(events)*eventSize]
+
+	count, err := this.chip.read(this.fd, data)
+	if err != nil {
+		return 0, err
+	}
+	if count%eventSize != 0 {
+		return 0, errors.New("partial gpio line event")
+	}
+	return count / eventSize, nil
+}
+
+// Flags for input lines with edge events and the bias named in settings
+func gpioLineFlags(settings SettingsData) uint64 {
+
+	flags := uint64(GPIO_V2_LINE_FLAG_INPUT | GPIO_V2_LINE_FLAG_ACTIVE_LOW |
+		GPIO_V2_LINE_FLAG_EDGE_RISING | GPIO_V2_LINE_FLAG_EDGE_FALLING | GPIO_V2_LINE_FLAG_EVENT_CLOCK_REALTIME)
+
+	switch strings.ToLower(settings.GpioBias) {
+	case "", "pull-up", "pullup":
+		flags |= GPIO_V2_LINE_FLAG_BIAS_PULL_UP
+	case "pull-down", "pulldown":
+		flags |= GPIO_V2_LINE_FLAG_BIAS_PULL_DOWN
+	case "disabled", "none":
+		flags |= GPIO_V2_LINE_FLAG_BIAS_DISABLED
+	case "as-is":
+	default:
+		log.Fatal("GpioBias must be pull-up, pull-down, disabled or as-is, not ", settings.GpioBias)
+	}
+
+	return flags
+}
+
+// Request the button lines and turn their edges into events, buttons are active low and pressed while the line is pulled to ground
+func (this *gpioChip) watchButtons(settings SettingsData, events *buttonEvents) error {
+
+	lines := []string{settings.LeftButtonLine, settings.RightButtonLine}
+	if lines[LeftButtonId] == "" {
+		lines[LeftButtonId] = settings.LeftButtonGpioPort
+	}
+	if lines[RightButtonId] == "" {
+		lines[RightButtonId] = settings.RightButtonGpioPort
+	}
+
+	// the inner buttons follow when both are wired
+	if settings.LeftInnerButtonLine != "" && settings.RightInnerButtonLine != "" {
+		lines = append(lines, settings.LeftInnerButtonLine, settings.RightInnerButtonLine)
+	}
+
+	offsets := make([]uint32, len(lines))
+	for button, line := range lines {
+		offset, err := this.findLine(line)
+		if err != nil {
+			return err
+		}
+		offsets[button] = offset
+	}
+
+	request, err := this.requestLines(offsets, gpioLineFlags(settings), events.debounce, "pongpi")
+	if err != nil {
+		return err
+	}
+
+	values, err := request.values()
+	if err != nil {
+		return err
+	}
+	for button := range offsets {
+		events.down[button] = values&(1<<uint(button)) != 0
+	}
+
+	// the kernel already debounces the lines
+	events.debounce = 0
+
+	go func() {
+		buffer := make([]gpioV2LineEvent, 16)
+		for {
+			count, err := request.readEvents(buffer)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			for _, event := range buffer[:count] {
+				at := time.Unix(0, int64(event.TimestampNs))
+				for button, offset := range offsets {
+					if offset == event.Offset {
+						events.update(ButtonId(button), event.Id == GPIO_V2_LINE_EVENT_RISING_EDGE, at)
+					}
+				}
+			}
+		}
+	}()
+
+	return nil
+}
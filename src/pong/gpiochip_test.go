@@ -0,0 +1,138 @@
+// +build !windows
+
+package pong
+
+import (
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// Fake gpiochip driver answering the ioctls the way the kernel does
+type fakeGpioKernel struct {
+	names []string
+
+	// values of every line, before active low is applied
+	levels uint64
+
+	// last line request
+	request gpioV2LineRequest
+
+	// edge events returned by reads of the request fd
+	events chan gpioV2LineEvent
+}
+
+const fakeChipFd, fakeRequestFd = 3, 4
+
+func (this *fakeGpioKernel) chip() *gpioChip {
+	return &gpioChip{fd: fakeChipFd, ioctl: this.ioctl, read: this.read}
+}
+
+func (this *fakeGpioKernel) ioctl(fd, request uintptr, arg unsafe.Pointer) error {
+	switch {
+	case fd == fakeChipFd && request == GPIO_GET_CHIPINFO_IOCTL:
+		info := (*gpiochipInfo)(arg)
+		copy(info.Name[:], "gpiochip0")
+		info.Lines = uint32(len(this.names))
+	case fd == fakeChipFd && request == GPIO_V2_GET_LINEINFO_IOCTL:
+		info := (*gpioV2LineInfo)(arg)
+		copy(info.Name[:], this.names[info.Offset])
+	case fd == fakeChipFd && request == GPIO_V2_GET_LINE_IOCTL:
+		this.request = *(*gpioV2LineRequest)(arg)
+		(*gpioV2LineRequest)(arg).Fd = fakeRequestFd
+	case fd == fakeRequestFd && request == GPIO_V2_LINE_GET_VALUES_IOCTL:
+		values := (*gpioV2LineValues)(arg)
+		values.Bits = 0
+		for index := uint32(0); index < this.request.NumLines; index++ {
+			level := this.levels>>this.request.Offsets[index]&1 != 0
+			if this.request.Config.Flags&GPIO_V2_LINE_FLAG_ACTIVE_LOW != 0 {
+				level = !level
+			}
+			if level {
+				values.Bits |= 1 << index
+			}
+		}
+		values.Bits &= values.Mask
+	default:
+		return syscall.ENOTTY
+	}
+	return nil
+}
+
+func (this *fakeGpioKernel) read(fd uintptr, data []byte) (int, error) {
+	event := <-this.events
+	size := int(unsafe.Sizeof(event))
+	copy(data, (*[1 << 16]byte)(unsafe.Pointer(&event))[:size])
+	return size, nil
+}
+
+func Test_GpioChip_StructSizes(t *testing.T) {
+	Assert(int(unsafe.Sizeof(gpiochipInfo{})), 68, "gpiochip_info", t)
+	Assert(int(unsafe.Sizeof(gpioV2LineRequest{})), 592, "gpio_v2_line_request", t)
+	Assert(int(unsafe.Sizeof(gpioV2LineInfo{})), 256, "gpio_v2_line_info", t)
+	Assert(int(unsafe.Sizeof(gpioV2LineEvent{})), 48, "gpio_v2_line_event", t)
+	Assert(int(unsafe.Sizeof(gpioV2LineValues{})), 16, "gpio_v2_line_values", t)
+	Assert(int(unsafe.Offsetof(gpioV2LineRequest{}.Config)), 288, "line request config offset", t)
+	Assert(int(unsafe.Offsetof(gpioV2LineRequest{}.Fd)), 588, "line request fd offset", t)
+}
+
+func Test_GpioChip_FindLine(t *testing.T) {
+	kernel := &fakeGpioKernel{names: []string{"ID_SDA", "ID_SCL", "GPIO2", "GPIO3"}}
+	chip := kernel.chip()
+
+	offset, err := chip.findLine("GPIO3")
+	Assert(int(offset), 3, "by name", t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offset, err = chip.findLine("1")
+	Assert(int(offset), 1, "by offset", t)
+
+	if _, err = chip.findLine("4"); err == nil {
+		t.Fatal("offset past the last line was found")
+	}
+	if _, err = chip.findLine("GPIO27"); err == nil {
+		t.Fatal("missing name was found")
+	}
+}
+
+func Test_GpioChip_WatchButtons(t *testing.T) {
+	kernel := &fakeGpioKernel{
+		names:  []string{"GPIO0", "GPIO1", "GPIO2", "GPIO3"},
+		levels: 1<<1 | 1<<2, // both buttons up, held high by the pull-up
+		events: make(chan gpioV2LineEvent),
+	}
+
+	settings := SettingsData{LeftButtonGpioPort: "2", RightButtonLine: "GPIO1", ButtonDebounceMs: 5}
+	events := newButtonEvents(settings)
+	if err := kernel.chip().watchButtons(settings, events); err != nil {
+		t.Fatal(err)
+	}
+
+	Assert(int(kernel.request.NumLines), 2, "lines requested", t)
+	Assert(int(kernel.request.Offsets[0]), 2, "left line", t)
+	Assert(int(kernel.request.Offsets[1]), 1, "right line", t)
+	flags := kernel.request.Config.Flags
+	Assert(int(flags&GPIO_V2_LINE_FLAG_BIAS_PULL_UP), GPIO_V2_LINE_FLAG_BIAS_PULL_UP, "pull-up", t)
+	Assert(int(flags&(GPIO_V2_LINE_FLAG_EDGE_RISING|GPIO_V2_LINE_FLAG_EDGE_FALLING)), GPIO_V2_LINE_FLAG_EDGE_RISING|GPIO_V2_LINE_FLAG_EDGE_FALLING, "both edges", t)
+	Assert(int(kernel.request.Config.Attrs[0].Attr.Id), GPIO_V2_LINE_ATTR_ID_DEBOUNCE, "debounce attribute", t)
+	Assert(int(kernel.request.Config.Attrs[0].Attr.Value), 5000, "debounce microseconds", t)
+	Assert(boolToInt(events.isDown(LeftButtonId)), 0, "left starts up", t)
+
+	pushed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	kernel.events <- gpioV2LineEvent{TimestampNs: uint64(pushed.UnixNano()), Id: GPIO_V2_LINE_EVENT_RISING_EDGE, Offset: 1}
+
+	select {
+	case press := <-events.events:
+		Assert(int(press.Button), int(RightButtonId), "button", t)
+		Assert(int(press.Event), int(ButtonPush), "event", t)
+		if !press.Time.Equal(pushed) {
+			t.Fatal("press at", press.Time, "vs expected", pushed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+	Assert(boolToInt(events.isDown(RightButtonId)), 1, "right down", t)
+}
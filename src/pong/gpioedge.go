@@ -0,0 +1,54 @@
+// +build linux
+
+package pong
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Waits for interrupts on sysfs gpio value files with epoll
+type edgeWaiter struct {
+	epollFd int
+	events  []syscall.EpollEvent
+}
+
+// Construct an edgeWaiter for the value files, their edge file must already be set
+func newEdgeWaiter(files ...*os.File) (*edgeWaiter, error) {
+
+	epollFd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		fd := int(file.Fd())
+		event := syscall.EpollEvent{Events: syscall.EPOLLPRI | syscall.EPOLLERR, Fd: int32(fd)}
+		if err := syscall.EpollCtl(epollFd, syscall.EPOLL_CTL_ADD, fd, &event); err != nil {
+			syscall.Close(epollFd)
+			return nil, err
+		}
+	}
+
+	return &edgeWaiter{
+		epollFd: epollFd,
+		events:  make([]syscall.EpollEvent, len(files)),
+	}, nil
+}
+
+// Block until an edge happens on any file or timeout passes, a negative timeout waits forever
+func (this *edgeWaiter) wait(timeout time.Duration) error {
+
+	milliseconds := -1
+	if timeout >= 0 {
+		milliseconds = int((timeout + time.Millisecond - 1) / time.Millisecond)
+	}
+
+	for {
+		_, err := syscall.EpollWait(this.epollFd, this.events, milliseconds)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// +build !linux,!windows
+
+package pong
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// Edge events need epoll, only available on linux
+type edgeWaiter struct {
+}
+
+// Always fails so the buttons are polled
+func newEdgeWaiter(files ...*os.File) (*edgeWaiter, error) {
+	return nil, errors.New("edge events need linux")
+}
+
+// Never called
+func (this *edgeWaiter) wait(timeout time.Duration) error {
+	return nil
+}
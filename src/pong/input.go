@@ -0,0 +1,118 @@
+package pong
+
+import (
+	"log"
+	"strings"
+	"sync"
+)
+
+// Buttons of the two players
+type InputSource interface {
+
+	// true while the left player's button is held down
+	LeftButton() bool
+
+	// true while the right player's button is held down
+	RightButton() bool
+
+	// true while any button is held down, including those of the inner players in doubles
+	ButtonDown(button ButtonId) bool
+
+	// push and release events, timestamped when they happened
+	Events() <-chan ButtonPress
+}
+
+var _ InputSource = &GpioReader{}
+
+// Construct the input described by settings
+func NewInput(input InputSettings, settings SettingsData) InputSource {
+
+	switch strings.ToLower(input.Type) {
+	case "gpio":
+		return NewGpioReader(settings)
+	case "keyboard":
+		return NewKeyboardInput(settings)
+	case "evdev":
+		return NewEvdevInput(input, settings)
+	}
+
+	log.Fatal("Unknown input ", input.Type)
+	return nil
+}
+
+// A display that also takes input, like the buttons on the web page
+type InputDisplay interface {
+	Display
+
+	// Buttons shown by the display
+	Input() InputSource
+}
+
+// Inputs of display and of every display it wraps
+func DisplayInputs(display Display) (inputs []InputSource) {
+
+	if inputDisplay, ok := display.(InputDisplay); ok {
+		inputs = append(inputs, inputDisplay.Input())
+	}
+
+	if wrapper, ok := display.(DisplayWrapper); ok {
+		for _, wrapped := range wrapper.WrappedDisplays() {
+			inputs = append(inputs, DisplayInputs(wrapped)...)
+		}
+	}
+
+	return
+}
+
+// Combines several sources, a button is down while it's down on any of them
+type mergedInput struct {
+	*buttonEvents
+
+	lock sync.Mutex
+
+	// state of each button on each source
+	sourceDown [][ButtonCount]bool
+}
+
+// Merge sources into one, returns the source itself when there is only one
+func MergeInputs(sources ...InputSource) InputSource {
+
+	if len(sources) == 1 {
+		return sources[0]
+	}
+
+	merged := &mergedInput{
+		buttonEvents: newButtonEvents(SettingsData{ButtonDebounceMs: -1}), // the sources already debounce
+		sourceDown:   make([][ButtonCount]bool, len(sources)),
+	}
+
+	for index, source := range sources {
+		for button := ButtonId(0); button < ButtonCount; button++ {
+			down := source.ButtonDown(button)
+			merged.sourceDown[index][button] = down
+			merged.down[button] = merged.down[button] || down
+		}
+	}
+
+	for index, source := range sources {
+		go merged.forward(index, source)
+	}
+
+	return merged
+}
+
+// Apply the events of the source at index, only changes of the combined state are passed on
+func (this *mergedInput) forward(index int, source InputSource) {
+	for press := range source.Events() {
+
+		this.lock.Lock()
+		this.sourceDown[index][press.Button] = press.Event == ButtonPush
+
+		down := false
+		for _, sourceDown := range this.sourceDown {
+			down = down || sourceDown[press.Button]
+		}
+		this.update(press.Button, down, press.Time)
+		this.lock.Unlock()
+	}
+}
@@ -0,0 +1,104 @@
+package pong
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// Wait for the next event on source
+func nextPress(source InputSource, t *testing.T) ButtonPress {
+	select {
+	case press := <-source.Events():
+		return press
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+	return ButtonPress{}
+}
+
+func Test_MergeInputs_AnySourceHoldsButton(t *testing.T) {
+	first := newButtonEvents(SettingsData{ButtonDebounceMs: -1})
+	second := newButtonEvents(SettingsData{ButtonDebounceMs: -1})
+	merged := MergeInputs(first, second)
+	start := time.Now()
+
+	first.update(LeftButtonId, true, start)
+	Assert(int(nextPress(merged, t).Event), int(ButtonPush), "first source pushes", t)
+
+	// the second source pushing and releasing while the first holds changes nothing
+	second.update(LeftButtonId, true, start.Add(time.Millisecond))
+	second.update(LeftButtonId, false, start.Add(2*time.Millisecond))
+	first.update(LeftButtonId, false, start.Add(3*time.Millisecond))
+
+	press := nextPress(merged, t)
+	Assert(int(press.Event), int(ButtonRelease), "released once both are up", t)
+	Assert(int(press.Time.Sub(start)/time.Millisecond), 3, "release time", t)
+	Assert(boolToInt(merged.LeftButton()), 0, "left up", t)
+}
+
+// Buttons already held when the sources are merged stay held until every source lets go, the inner ones too
+func Test_MergeInputs_HeldBefore(t *testing.T) {
+	first := newButtonEvents(SettingsData{ButtonDebounceMs: -1})
+	second := newButtonEvents(SettingsData{ButtonDebounceMs: -1})
+	start := time.Now()
+
+	first.update(LeftInnerButtonId, true, start)
+	second.update(RightInnerButtonId, true, start)
+	<-first.Events()
+	<-second.Events()
+
+	merged := MergeInputs(first, second)
+	for button := ButtonId(0); button < ButtonCount; button++ {
+		expected := button == LeftInnerButtonId || button == RightInnerButtonId
+		Assert(boolToInt(merged.ButtonDown(button)), boolToInt(expected), "held when merged", t)
+	}
+
+	second.update(RightInnerButtonId, false, start.Add(time.Millisecond))
+	press := nextPress(merged, t)
+	Assert(int(press.Button), int(RightInnerButtonId), "button", t)
+	Assert(int(press.Event), int(ButtonRelease), "released", t)
+}
+
+func Test_MergeInputs_Single(t *testing.T) {
+	source := newButtonEvents(SettingsData{})
+	if MergeInputs(source) != InputSource(source) {
+		t.Fatal("a single source should be used as is")
+	}
+}
+
+func Test_KeyboardInput_HoldsTypedKey(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	keyboard := newKeyboardInput(reader, SettingsData{LeftKeys: "az", KeyHoldMs: 50})
+
+	writer.Write([]byte("x"))
+	writer.Write([]byte("Z"))
+	press := nextPress(keyboard, t)
+	Assert(int(press.Button), int(LeftButtonId), "button", t)
+	Assert(int(press.Event), int(ButtonPush), "typed", t)
+
+	release := nextPress(keyboard, t)
+	Assert(int(release.Event), int(ButtonRelease), "released", t)
+	Assert(int(release.Time.Sub(press.Time)/time.Millisecond), 50, "held for the hold time", t)
+}
+
+// The inner players of doubles have keys of their own, s and k by default
+func Test_KeyboardInput_InnerKeys(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	keyboard := newKeyboardInput(reader, SettingsData{LeftInnerKeys: "q", KeyHoldMs: 50})
+
+	writer.Write([]byte("k"))
+	press := nextPress(keyboard, t)
+	Assert(int(press.Button), int(RightInnerButtonId), "default right inner key", t)
+
+	writer.Write([]byte("q"))
+	for press.Button != LeftInnerButtonId {
+		press = nextPress(keyboard, t)
+	}
+	Assert(int(press.Event), int(ButtonPush), "left inner key typed", t)
+	Assert(boolToInt(LeftInnerButtonId.IsLeft()), 1, "left inner is on the left", t)
+}
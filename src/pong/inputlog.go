@@ -0,0 +1,38 @@
+package pong
+
+import (
+	"time"
+)
+
+// A button press and the step of the SceneRunner it was handled at
+type LoggedPress struct {
+
+	// steps since the log started, and how long after the start of that step the press happened
+	Step   int64
+	Offset time.Duration
+
+	Button ButtonId
+	Event  ButtonEvent
+}
+
+// Button presses of a game, replaying them from the same seed and scene plays the same game again
+type InputLog struct {
+
+	// seed of the Random the game was played with
+	Seed uint64
+
+	// step of the SceneRunner the log started at
+	Start int64
+
+	Presses []LoggedPress
+}
+
+// Construct an InputLog for a game played with random numbers from seed
+func NewInputLog(seed uint64) *InputLog {
+	return &InputLog{Seed: seed}
+}
+
+// Record press handled at step of the SceneRunner, offset after the start of the step
+func (this *InputLog) Add(step int64, offset time.Duration, press ButtonPress) {
+	this.Presses = append(this.Presses, LoggedPress{Step: step - this.Start, Offset: offset, Button: press.Button, Event: press.Event})
+}
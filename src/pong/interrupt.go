@@ -0,0 +1,42 @@
+package pong
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// functions run before the process exits, see AtExit
+var cleanups []func()
+var cleanupLock sync.Mutex
+var interruptOnce sync.Once
+
+// Register cleanup to run by Cleanup, and when the process is interrupted before exiting
+func AtExit(cleanup func()) {
+	cleanupLock.Lock()
+	cleanups = append(cleanups, cleanup)
+	cleanupLock.Unlock()
+
+	interruptOnce.Do(func() {
+		interrupts := make(chan os.Signal, 1)
+		signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-interrupts
+			Cleanup()
+			os.Exit(1)
+		}()
+	})
+}
+
+// Run the functions registered with AtExit once, the last registered first
+func Cleanup() {
+	cleanupLock.Lock()
+	pending := cleanups
+	cleanups = nil
+	cleanupLock.Unlock()
+
+	for index := len(pending) - 1; index >= 0; index-- {
+		pending[index]()
+	}
+}
@@ -0,0 +1,125 @@
+package pong
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// key hold time used when KeyHoldMs isn't set
+const defaultKeyHold = 200 * time.Millisecond
+
+// Buttons on the terminal keyboard. Terminals don't report key releases, so a key counts as held until
+// no key repeat arrived for the hold time
+type KeyboardInput struct {
+	*buttonEvents
+
+	// keys of each player
+	keys map[rune]ButtonId
+
+	// how long a key stays down after it was typed
+	hold time.Duration
+
+	// time each button was last typed
+	typed [ButtonCount]time.Time
+}
+
+// a key read from the terminal
+type keyPress struct {
+	key rune
+	at  time.Time
+}
+
+// Read the keyboard on stdin, the terminal is restored on Cleanup or when the process is interrupted
+func NewKeyboardInput(settings SettingsData) *KeyboardInput {
+
+	restore, err := rawTerminal()
+	if err != nil {
+		log.Fatal("Can't read the keyboard ", err)
+	}
+
+	AtExit(restore)
+
+	return newKeyboardInput(os.Stdin, settings)
+}
+
+// Construct a KeyboardInput reading keys from reader
+func newKeyboardInput(reader io.Reader, settings SettingsData) *KeyboardInput {
+
+	keys := [ButtonCount]string{settings.LeftKeys, settings.RightKeys, settings.LeftInnerKeys, settings.RightInnerKeys}
+	for button, defaultKey := range [ButtonCount]string{"a", "l", "s", "k"} {
+		if keys[button] == "" {
+			keys[button] = defaultKey
+		}
+	}
+
+	keyboard := &KeyboardInput{
+		buttonEvents: newButtonEvents(SettingsData{ButtonDebounceMs: -1}),
+		keys:         make(map[rune]ButtonId),
+		hold:         defaultKeyHold,
+	}
+	if settings.KeyHoldMs > 0 {
+		keyboard.hold = time.Duration(settings.KeyHoldMs * float64(time.Millisecond))
+	}
+
+	for button, buttonKeys := range keys {
+		for _, key := range strings.ToLower(buttonKeys) {
+			keyboard.keys[key] = ButtonId(button)
+		}
+	}
+
+	presses := make(chan keyPress, buttonEventBuffer)
+	go readKeys(reader, presses)
+	go keyboard.run(presses)
+
+	return keyboard
+}
+
+// Send every key read from reader on keys
+func readKeys(reader io.Reader, keys chan<- keyPress) {
+
+	buffered := bufio.NewReader(reader)
+	for {
+		key, _, err := buffered.ReadRune()
+		if err != nil {
+			if err != io.EOF {
+				log.Print("Keyboard input stopped ", err)
+			}
+			close(keys)
+			return
+		}
+		keys <- keyPress{key: key, at: time.Now()}
+	}
+}
+
+// Push buttons as their keys are typed and release them once the hold time passed
+func (this *KeyboardInput) run(keys <-chan keyPress) {
+
+	releases := time.NewTicker(this.hold / 10)
+	defer releases.Stop()
+
+	for {
+		select {
+		case press, ok := <-keys:
+			if !ok {
+				return
+			}
+			button, ok := this.keys[[]rune(strings.ToLower(string(press.key)))[0]]
+			if !ok {
+				continue
+			}
+			this.typed[button] = press.at
+			this.update(button, true, press.at)
+
+		case now := <-releases.C:
+			for button, typed := range this.typed {
+				if this.isDown(ButtonId(button)) && now.Sub(typed) >= this.hold {
+					this.update(ButtonId(button), false, typed.Add(this.hold))
+				}
+			}
+		}
+	}
+}
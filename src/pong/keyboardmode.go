@@ -0,0 +1,33 @@
+// +build !windows
+
+package pong
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Switch the terminal on stdin to unbuffered input without echo, returns a function that restores it
+func rawTerminal() (restore func(), err error) {
+
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+
+	// keep signal handling so ctrl+c still works
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+
+	return func() { stty(strings.TrimSpace(saved)) }, nil
+}
+
+// Run stty on the terminal attached to stdin
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	return string(output), err
+}
@@ -0,0 +1,33 @@
+// +build windows
+
+package pong
+
+import (
+	"os"
+	"syscall"
+)
+
+// Console modes from wincon.h
+const (
+	ENABLE_LINE_INPUT uint32 = 0x0002
+	ENABLE_ECHO_INPUT uint32 = 0x0004
+)
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// Switch the console on stdin to unbuffered input without echo, returns a function that restores it
+func rawTerminal() (restore func(), err error) {
+
+	handle := syscall.Handle(os.Stdin.Fd())
+
+	var saved uint32
+	if err := syscall.GetConsoleMode(handle, &saved); err != nil {
+		return nil, err
+	}
+
+	if result, _, err := setConsoleMode.Call(uintptr(handle), uintptr(saved&^(ENABLE_LINE_INPUT|ENABLE_ECHO_INPUT))); result == 0 {
+		return nil, err
+	}
+
+	return func() { setConsoleMode.Call(uintptr(handle), uintptr(saved)) }, nil
+}
@@ -0,0 +1,77 @@
+package pong
+
+// Rounds of a best of N match between the left and right player, the serve alternates between rounds
+type Match struct {
+
+	// most rounds that can be played
+	bestOf int
+
+	// rounds won by each player
+	leftRounds, rightRounds int
+
+	// true while the left player serves
+	leftServes bool
+}
+
+// Construct a Match of bestOf rounds, an even number is rounded up
+func NewMatch(bestOf int, leftServesFirst bool) *Match {
+
+	if bestOf < 1 {
+		bestOf = 1
+	}
+	if bestOf%2 == 0 {
+		bestOf++
+	}
+
+	return &Match{
+		bestOf:     bestOf,
+		leftServes: leftServesFirst,
+	}
+}
+
+// Rounds a player needs to win the match
+func (this *Match) RoundsToWin() int {
+	return this.bestOf/2 + 1
+}
+
+// Number of the current round, starting at 1
+func (this *Match) Round() int {
+	return this.leftRounds + this.rightRounds + 1
+}
+
+// True if the left player serves the current round
+func (this *Match) LeftServes() bool {
+	return this.leftServes
+}
+
+// Rounds won by the left and right player
+func (this *Match) Score() (left, right int) {
+	return this.leftRounds, this.rightRounds
+}
+
+// True once a player won enough rounds
+func (this *Match) IsOver() bool {
+	return this.leftRounds >= this.RoundsToWin() || this.rightRounds >= this.RoundsToWin()
+}
+
+// True if the left player won the match
+func (this *Match) LeftWon() bool {
+	return this.leftRounds >= this.RoundsToWin()
+}
+
+// Record the winner of the current round and hand the serve to the other player, returns true if the match is over
+func (this *Match) EndRound(leftWon bool) (matchOver bool) {
+
+	if this.IsOver() {
+		return true
+	}
+
+	if leftWon {
+		this.leftRounds++
+	} else {
+		this.rightRounds++
+	}
+	this.leftServes = !this.leftServes
+
+	return this.IsOver()
+}
@@ -0,0 +1,41 @@
+package pong
+
+import (
+	"testing"
+)
+
+func Test_Match_BestOfThree(t *testing.T) {
+	match := NewMatch(3, true)
+	Assert(match.RoundsToWin(), 2, "rounds to win", t)
+	Assert(boolToInt(match.LeftServes()), 1, "left serves first", t)
+
+	Assert(boolToInt(match.EndRound(false)), 0, "not over after one round", t)
+	Assert(boolToInt(match.LeftServes()), 0, "serve alternates", t)
+	Assert(match.Round(), 2, "second round", t)
+
+	match.EndRound(true)
+	Assert(boolToInt(match.LeftServes()), 1, "serve alternates back", t)
+
+	Assert(boolToInt(match.EndRound(true)), 1, "over after the deciding round", t)
+	left, right := match.Score()
+	Assert(left, 2, "left rounds", t)
+	Assert(right, 1, "right rounds", t)
+	Assert(boolToInt(match.LeftWon()), 1, "left won", t)
+
+	// rounds after the match is over are ignored
+	match.EndRound(false)
+	_, right = match.Score()
+	Assert(right, 1, "right rounds after the match", t)
+}
+
+func Test_Match_SingleRound(t *testing.T) {
+	for _, bestOf := range []int{0, 1} {
+		match := NewMatch(bestOf, false)
+		Assert(boolToInt(match.EndRound(false)), 1, "one round decides", t)
+		Assert(boolToInt(match.LeftWon()), 0, "right won", t)
+	}
+}
+
+func Test_Match_EvenRoundsUp(t *testing.T) {
+	Assert(NewMatch(4, false).RoundsToWin(), 3, "best of 4 plays like best of 5", t)
+}
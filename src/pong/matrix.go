@@ -0,0 +1,168 @@
+package pong
+
+import (
+	"log"
+	"strings"
+)
+
+// Ways the leds of a matrix panel can be wired, used in SettingsData.MatrixLayout
+const (
+	RowMajor         = "rowmajor"         // every row runs left to right
+	Serpentine       = "serpentine"       // rows alternate left to right and right to left, also called zigzag
+	ColumnMajor      = "columnmajor"      // every column runs top to bottom
+	ColumnSerpentine = "columnserpentine" // columns alternate top to bottom and bottom to top
+)
+
+// Ways the field can be shown on a matrix, used in SettingsData.MatrixMode
+const (
+	MatrixTall = "tall" // the strip runs along the matrix and is mirrored across every row
+	Matrix2D   = "2d"   // the field has height and covers the whole matrix
+)
+
+// Maps x, y positions of the field to the index of the led on the strip
+type PixelMap struct {
+
+	// size of the field, before rotation
+	width, height int
+
+	// strip index of each position, stored row by row
+	indices []int
+}
+
+// Build the map for a field of width x height shown rotated clockwise by rotation degrees on a panel wired with layout
+func NewPixelMap(width, height int, layout string, rotation int) *PixelMap {
+
+	// size of the panel itself
+	panelWidth, panelHeight := width, height
+	if rotation == 90 || rotation == 270 {
+		panelWidth, panelHeight = height, width
+	}
+
+	pixels := &PixelMap{
+		width:   width,
+		height:  height,
+		indices: make([]int, width*height),
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+
+			var panelX, panelY int
+			switch rotation {
+			case 0:
+				panelX, panelY = x, y
+			case 90:
+				panelX, panelY = height-1-y, x
+			case 180:
+				panelX, panelY = width-1-x, height-1-y
+			case 270:
+				panelX, panelY = y, width-1-x
+			default:
+				log.Fatal("Matrix rotation must be 0, 90, 180 or 270, not ", rotation)
+			}
+
+			pixels.indices[y*width+x] = wiringIndex(panelX, panelY, panelWidth, panelHeight, layout)
+		}
+	}
+
+	return pixels
+}
+
+// Index along the strip of the led at x, y on the panel
+func wiringIndex(x, y, panelWidth, panelHeight int, layout string) int {
+
+	switch strings.ToLower(layout) {
+	case "", RowMajor:
+		return y*panelWidth + x
+	case Serpentine, "zigzag":
+		if y%2 == 1 {
+			x = panelWidth - 1 - x
+		}
+		return y*panelWidth + x
+	case ColumnMajor:
+		return x*panelHeight + y
+	case ColumnSerpentine:
+		if x%2 == 1 {
+			y = panelHeight - 1 - y
+		}
+		return x*panelHeight + y
+	}
+
+	log.Fatal("Unknown matrix layout ", layout)
+	return 0
+}
+
+// Strip index of the led at x, y of the field
+func (this *PixelMap) Index(x, y int) int {
+	return this.indices[y*this.width+x]
+}
+
+// Display decorator that reorders frames from field order to the wiring order of a matrix panel
+type MatrixDisplay struct {
+	display Display
+
+	pixels *PixelMap
+
+	// frame in strip order
+	buffer []RGBA
+}
+
+var _ StatusDisplay = &MatrixDisplay{}
+var _ DisplayWrapper = &MatrixDisplay{}
+
+// Wrap display with a MatrixDisplay when settings describe a matrix, otherwise return display unchanged
+func MapToMatrix(display Display, settings SettingsData) Display {
+
+	if !settings.IsMatrix() {
+		return display
+	}
+
+	pixels := NewPixelMap(settings.MatrixWidth, settings.MatrixHeight, settings.MatrixLayout, settings.MatrixRotation)
+	return NewMatrixDisplay(display, pixels)
+}
+
+// Construct a MatrixDisplay
+func NewMatrixDisplay(display Display, pixels *PixelMap) *MatrixDisplay {
+	return &MatrixDisplay{
+		display: display,
+		pixels:  pixels,
+		buffer:  make([]RGBA, len(pixels.indices)),
+	}
+}
+
+// Reorder colors and render them. A frame of a single row is mirrored across every row
+func (this *MatrixDisplay) Render(colors []RGBA) {
+
+	width, height := this.pixels.width, this.pixels.height
+
+	switch len(colors) {
+	case width:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				this.buffer[this.pixels.Index(x, y)] = colors[x]
+			}
+		}
+	case width * height:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				this.buffer[this.pixels.Index(x, y)] = colors[y*width+x]
+			}
+		}
+	default:
+		log.Fatal("Matrix of ", width, "x", height, " can't show a frame of ", len(colors), " colors")
+	}
+
+	this.display.Render(this.buffer)
+}
+
+// The display in strip order
+func (this *MatrixDisplay) WrappedDisplays() []Display {
+	return []Display{this.display}
+}
+
+// Forward the status to the wrapped display
+func (this *MatrixDisplay) SetStatus(status string) {
+	if statusDisplay, ok := this.display.(StatusDisplay); ok {
+		statusDisplay.SetStatus(status)
+	}
+}
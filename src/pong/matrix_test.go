@@ -0,0 +1,75 @@
+package pong
+
+import (
+	"testing"
+)
+
+// Check the strip index of every position of a 3 x 2 field
+func assertPixelMap(pixels *PixelMap, expected [][]int, name string, t *testing.T) {
+	for y, row := range expected {
+		for x, index := range row {
+			if actual := pixels.Index(x, y); actual != index {
+				t.Fatal(name, "position", x, y, "mapped to", actual, "expected", index)
+			}
+		}
+	}
+}
+
+func Test_PixelMap_Layouts(t *testing.T) {
+	assertPixelMap(NewPixelMap(3, 2, RowMajor, 0), [][]int{
+		{0, 1, 2},
+		{3, 4, 5},
+	}, RowMajor, t)
+
+	assertPixelMap(NewPixelMap(3, 2, Serpentine, 0), [][]int{
+		{0, 1, 2},
+		{5, 4, 3},
+	}, Serpentine, t)
+
+	assertPixelMap(NewPixelMap(3, 2, ColumnMajor, 0), [][]int{
+		{0, 2, 4},
+		{1, 3, 5},
+	}, ColumnMajor, t)
+
+	assertPixelMap(NewPixelMap(3, 2, ColumnSerpentine, 0), [][]int{
+		{0, 3, 4},
+		{1, 2, 5},
+	}, ColumnSerpentine, t)
+}
+
+// A 3 x 2 field rotated onto a row major panel
+func Test_PixelMap_Rotation(t *testing.T) {
+	// panel is 2 wide and 3 tall, field row 0 runs down its right column
+	assertPixelMap(NewPixelMap(3, 2, RowMajor, 90), [][]int{
+		{1, 3, 5},
+		{0, 2, 4},
+	}, "90", t)
+
+	assertPixelMap(NewPixelMap(3, 2, RowMajor, 180), [][]int{
+		{5, 4, 3},
+		{2, 1, 0},
+	}, "180", t)
+
+	assertPixelMap(NewPixelMap(3, 2, RowMajor, 270), [][]int{
+		{4, 2, 0},
+		{5, 3, 1},
+	}, "270", t)
+}
+
+// A single row is mirrored on every row, a full frame is reordered
+func Test_MatrixDisplay_Render(t *testing.T) {
+	strip := &collectingDisplay{}
+	display := NewMatrixDisplay(strip, NewPixelMap(3, 2, Serpentine, 0))
+
+	display.Render([]RGBA{{1, 0, 0, 255}, {2, 0, 0, 255}, {3, 0, 0, 255}})
+	expected := []uint8{1, 2, 3, 3, 2, 1}
+	for index, red := range expected {
+		Assert(int(strip.frames[0][index].R), int(red), "Mirrored led", t)
+	}
+
+	display.Render([]RGBA{{1, 0, 0, 255}, {2, 0, 0, 255}, {3, 0, 0, 255}, {4, 0, 0, 255}, {5, 0, 0, 255}, {6, 0, 0, 255}})
+	expected = []uint8{1, 2, 3, 6, 5, 4}
+	for index, red := range expected {
+		Assert(int(strip.frames[1][index].R), int(red), "Full frame led", t)
+	}
+}
@@ -0,0 +1,114 @@
+package pong
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// Forwards every frame to several displays, each driven from its own goroutine so a slow one can't hold up the others
+type MultiDisplay struct {
+
+	// latest frame, shared by every output
+	frames *FrameStore
+
+	outputs []*multiDisplayOutput
+}
+
+// A display driven by a MultiDisplay
+type multiDisplayOutput struct {
+	display Display
+
+	// minimum time between frames sent to display, 0 for no limit
+	minFrameTime time.Duration
+}
+
+var _ StatusDisplay = &MultiDisplay{}
+var _ DisplayWrapper = &MultiDisplay{}
+
+// Construct a MultiDisplay without any outputs
+func NewMultiDisplay() *MultiDisplay {
+	return &MultiDisplay{
+		frames: NewFrameStore(),
+	}
+}
+
+// Add a display that receives at most maxFPS frames per second, 0 for no limit
+func (this *MultiDisplay) Add(display Display, maxFPS float64) {
+
+	output := &multiDisplayOutput{
+		display: display,
+	}
+	if maxFPS > 0 {
+		output.minFrameTime = time.Duration(float64(time.Second) / maxFPS)
+	}
+
+	this.outputs = append(this.outputs, output)
+	go output.run(this.frames)
+}
+
+// Publish the frame to every output, never blocks on them
+func (this *MultiDisplay) Render(colors []RGBA) {
+	this.frames.Store(colors)
+}
+
+// Every output
+func (this *MultiDisplay) WrappedDisplays() []Display {
+	displays := make([]Display, len(this.outputs))
+	for index, output := range this.outputs {
+		displays[index] = output.display
+	}
+	return displays
+}
+
+// Forward the status to every output that can show it
+func (this *MultiDisplay) SetStatus(status string) {
+	for _, output := range this.outputs {
+		if statusDisplay, ok := output.display.(StatusDisplay); ok {
+			statusDisplay.SetStatus(status)
+		}
+	}
+}
+
+// Render the newest frame whenever one is available, skipping any that arrive while display is busy
+func (this *multiDisplayOutput) run(frames *FrameStore) {
+
+	var sequence uint64
+	for {
+		frame := frames.WaitNewer(sequence, time.Second)
+		if frame == nil {
+			continue
+		}
+		sequence = frame.Sequence
+
+		renderStart := time.Now()
+		this.display.Render(frame.Colors)
+
+		if wait := this.minFrameTime - time.Since(renderStart); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+}
+
+// Construct the display described by output: led, web, terminal, or one of the network protocols opc, e131 and artnet
+func NewOutputDisplay(output OutputSettings, settings SettingsData) Display {
+
+	if output.Address != "" {
+		settings.NetworkAddress = output.Address
+	}
+
+	switch strings.ToLower(output.Type) {
+	case "led":
+		return MapToMatrix(NewLedDisplay(settings), settings)
+	case "web":
+		return NewWebDisplay(settings)
+	case "terminal":
+		return NewTerminalDisplay(settings)
+	case OpenPixelControl, E131, "sacn", ArtNet:
+		settings.NetworkProtocol = output.Type
+		return MapToMatrix(NewNetworkDisplay(settings), settings)
+	}
+
+	log.Fatal("Unknown output type ", output.Type)
+	return nil
+}
@@ -0,0 +1,93 @@
+package pong
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Display that takes delay to render and remembers the last frame
+type slowDisplay struct {
+	delay time.Duration
+
+	lock   sync.Mutex
+	count  int
+	latest RGBA
+}
+
+func (display *slowDisplay) Render(colors []RGBA) {
+	time.Sleep(display.delay)
+
+	display.lock.Lock()
+	defer display.lock.Unlock()
+	display.count++
+	display.latest = colors[0]
+}
+
+func (display *slowDisplay) state() (int, RGBA) {
+	display.lock.Lock()
+	defer display.lock.Unlock()
+	return display.count, display.latest
+}
+
+// Wait until display has shown the expected color
+func waitForColor(display *slowDisplay, expected RGBA, t *testing.T) {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if _, latest := display.state(); latest == expected {
+			return
+		}
+	}
+	t.Fatal("Display never showed", expected)
+}
+
+// A slow output must not slow down Render or the other outputs, it just skips frames
+func Test_MultiDisplay_SlowOutput(t *testing.T) {
+	fast := &slowDisplay{}
+	slow := &slowDisplay{delay: 200 * time.Millisecond}
+
+	multi := NewMultiDisplay()
+	multi.Add(fast, 0)
+	multi.Add(slow, 0)
+
+	start := time.Now()
+	for frame := 1; frame <= 20; frame++ {
+		multi.Render([]RGBA{{uint8(frame), 0, 0, 255}})
+		time.Sleep(2 * time.Millisecond)
+	}
+	if time.Since(start) > 150*time.Millisecond {
+		t.Fatal("Render was held up by the slow output, took", time.Since(start))
+	}
+
+	last := RGBA{20, 0, 0, 255}
+	waitForColor(fast, last, t)
+	waitForColor(slow, last, t)
+
+	if count, _ := slow.state(); count > 3 {
+		t.Fatal("Slow output should have skipped frames, rendered", count)
+	}
+}
+
+// An output with a frame rate cap renders fewer frames than one without
+func Test_MultiDisplay_FrameRateCap(t *testing.T) {
+	uncapped := &slowDisplay{}
+	capped := &slowDisplay{}
+
+	multi := NewMultiDisplay()
+	multi.Add(uncapped, 0)
+	multi.Add(capped, 10)
+
+	for frame := 1; frame <= 50; frame++ {
+		multi.Render([]RGBA{{uint8(frame), 0, 0, 255}})
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	last := RGBA{50, 0, 0, 255}
+	waitForColor(uncapped, last, t)
+	waitForColor(capped, last, t)
+
+	cappedCount, _ := capped.state()
+	uncappedCount, _ := uncapped.state()
+	if cappedCount >= uncappedCount || cappedCount > 5 {
+		t.Fatal("Capped output rendered", cappedCount, "frames, uncapped", uncappedCount)
+	}
+}
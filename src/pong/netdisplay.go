@@ -0,0 +1,403 @@
+package pong
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+// Names of the supported network protocols, used in SettingsData.NetworkProtocol
+const (
+	OpenPixelControl = "opc"
+	E131             = "e131"
+	ArtNet           = "artnet"
+)
+
+// Default ports of each protocol
+const (
+	opcDefaultPort    = 7890
+	e131DefaultPort   = 5568
+	artNetDefaultPort = 6454
+)
+
+// Construct the network Display named in settings
+func NewNetworkDisplay(settings SettingsData) Display {
+
+	switch strings.ToLower(settings.NetworkProtocol) {
+	case OpenPixelControl:
+		return NewOpcDisplay(settings)
+	case E131, "sacn":
+		return NewE131Display(settings)
+	case ArtNet:
+		return NewArtNetDisplay(settings)
+	}
+
+	log.Fatal("Unknown network protocol ", settings.NetworkProtocol)
+	return nil
+}
+
+// Adds the default port to address if it doesn't have one
+func withDefaultPort(address string, port int) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(address, fmt.Sprint(port))
+}
+
+// The leds sent in a single universe
+type universeSpan struct {
+
+	// DMX universe number
+	universe int
+
+	// first channel in the universe that holds led data, 0 based
+	startChannel int
+
+	// range of leds sent in this universe
+	firstLed, ledCount int
+}
+
+// Split ledCount leds across as many universes as needed, a led is never split between two universes
+func splitUniverses(ledCount, startUniverse, startChannel, channelsPerUniverse int) (spans []universeSpan) {
+
+	if channelsPerUniverse <= 0 || 512 < channelsPerUniverse {
+		channelsPerUniverse = 510
+	}
+	if startChannel < 0 || channelsPerUniverse-3 < startChannel {
+		startChannel = 0
+	}
+
+	universe := startUniverse
+	for firstLed := 0; firstLed < ledCount; universe++ {
+
+		count := (channelsPerUniverse - startChannel) / 3
+		if firstLed+count > ledCount {
+			count = ledCount - firstLed
+		}
+
+		spans = append(spans, universeSpan{
+			universe:     universe,
+			startChannel: startChannel,
+			firstLed:     firstLed,
+			ledCount:     count,
+		})
+
+		firstLed += count
+		startChannel = 0 // only the first universe is offset
+	}
+
+	return
+}
+
+// Copy the RGB values of the leds in span into data, starting at the span's channel. Leds past the end of a frame
+// shorter than the span are sent black
+func (span universeSpan) fill(colors []RGBA, data []byte) {
+	for index := 0; index < span.ledCount; index++ {
+		var color RGBA
+		if led := span.firstLed + index; led < len(colors) {
+			color = colors[led]
+		}
+		channel := span.startChannel + index*3
+
+		data[channel+0] = color.R
+		data[channel+1] = color.G
+		data[channel+2] = color.B
+	}
+}
+
+// Number of DMX slots needed for span
+func (span universeSpan) slotCount() int {
+	return span.startChannel + span.ledCount*3
+}
+
+// Open Pixel Control over TCP, see http://openpixelcontrol.org
+type OpcDisplay struct {
+	address string
+
+	// OPC channel, 0 sends to every channel
+	channel uint8
+
+	// nil while disconnected
+	conn net.Conn
+
+	// last time a connection was attempted, limits reconnect attempts
+	lastDial time.Time
+
+	packet []byte
+}
+
+var _ Display = &OpcDisplay{}
+
+// time between reconnect attempts when the server is down
+const opcReconnectInterval = time.Second
+
+// time allowed to connect or write a frame, keeps a stalled server from stalling the game
+const networkTimeout = 100 * time.Millisecond
+
+// Construct an OpcDisplay, the connection is made on the first Render
+func NewOpcDisplay(settings SettingsData) *OpcDisplay {
+	return &OpcDisplay{
+		address: withDefaultPort(settings.NetworkAddress, opcDefaultPort),
+		channel: uint8(settings.OpcChannel),
+		packet:  make([]byte, 4+settings.LedCount*3),
+	}
+}
+
+// Send the colors as a set pixel colors message
+func (this *OpcDisplay) Render(colors []RGBA) {
+
+	if this.conn == nil {
+		if time.Since(this.lastDial) < opcReconnectInterval {
+			return
+		}
+		this.lastDial = time.Now()
+
+		conn, err := net.DialTimeout("tcp", this.address, networkTimeout)
+		if err != nil {
+			log.Print("Unable to connect to OPC server ", err)
+			return
+		}
+		log.Print("Connected to OPC server ", this.address)
+		this.conn = conn
+	}
+
+	length := len(colors) * 3
+	if len(this.packet) != 4+length {
+		this.packet = make([]byte, 4+length)
+	}
+
+	this.packet[0] = this.channel
+	this.packet[1] = 0 // set pixel colors
+	binary.BigEndian.PutUint16(this.packet[2:4], uint16(length))
+	universeSpan{firstLed: 0, ledCount: len(colors)}.fill(colors, this.packet[4:])
+
+	this.conn.SetWriteDeadline(time.Now().Add(networkTimeout))
+	if _, err := this.conn.Write(this.packet); err != nil {
+		log.Print("Lost connection to OPC server ", err)
+		this.conn.Close()
+		this.conn = nil
+	}
+}
+
+// Sends universes over UDP, shared by E1.31 and Art-Net
+type udpUniverseSender struct {
+	conn *net.UDPConn
+
+	spans []universeSpan
+
+	// where each span is sent
+	destinations []*net.UDPAddr
+
+	// packet buffer for each span
+	packets [][]byte
+}
+
+// Open a UDP socket and resolve the destination of each span
+func newUdpUniverseSender(spans []universeSpan, destination func(universe int) string) *udpUniverseSender {
+
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sender := &udpUniverseSender{
+		conn:         conn,
+		spans:        spans,
+		destinations: make([]*net.UDPAddr, len(spans)),
+		packets:      make([][]byte, len(spans)),
+	}
+
+	for index, span := range spans {
+		sender.destinations[index], err = net.ResolveUDPAddr("udp", destination(span.universe))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	return sender
+}
+
+// Send each packet to its universe's destination
+func (this *udpUniverseSender) send() {
+	for index, packet := range this.packets {
+		this.conn.SetWriteDeadline(time.Now().Add(networkTimeout))
+		if _, err := this.conn.WriteToUDP(packet, this.destinations[index]); err != nil {
+			log.Print("Failed to send universe ", this.spans[index].universe, " ", err)
+		}
+	}
+}
+
+// E1.31 (streaming ACN) over UDP, multicast when no address is configured
+type E131Display struct {
+	*udpUniverseSender
+
+	// sequence number, incremented once per frame
+	sequence uint8
+}
+
+var _ Display = &E131Display{}
+
+// size of the E1.31 headers before the DMX data
+const e131HeaderSize = 126
+
+// Construct an E131Display
+func NewE131Display(settings SettingsData) *E131Display {
+
+	startUniverse := settings.NetworkStartUniverse
+	if startUniverse < 1 {
+		startUniverse = 1 // universe 0 is reserved in E1.31
+	}
+	spans := splitUniverses(settings.LedCount, startUniverse, settings.NetworkStartChannel, settings.NetworkChannelsPerUniverse)
+
+	destination := func(universe int) string {
+		if settings.NetworkAddress == "" {
+			return fmt.Sprintf("239.255.%d.%d:%d", universe>>8, universe&0xFF, e131DefaultPort)
+		}
+		return withDefaultPort(settings.NetworkAddress, e131DefaultPort)
+	}
+
+	display := &E131Display{
+		udpUniverseSender: newUdpUniverseSender(spans, destination),
+	}
+
+	// every source needs a unique id
+	var cid [16]byte
+	if _, err := rand.Read(cid[:]); err != nil {
+		log.Fatal(err)
+	}
+
+	for index, span := range spans {
+		display.packets[index] = newE131Packet(cid, "pongpi", span.universe, span.slotCount())
+	}
+
+	return display
+}
+
+// Build an E1.31 data packet with every header filled in except the sequence number
+func newE131Packet(cid [16]byte, sourceName string, universe int, slotCount int) []byte {
+
+	packet := make([]byte, e131HeaderSize+slotCount)
+	length := len(packet)
+
+	// root layer
+	binary.BigEndian.PutUint16(packet[0:], 0x0010) // preamble size
+	binary.BigEndian.PutUint16(packet[2:], 0x0000) // postamble size
+	copy(packet[4:16], "ASC-E1.17\x00\x00\x00")
+	binary.BigEndian.PutUint16(packet[16:], 0x7000|uint16(length-16))
+	binary.BigEndian.PutUint32(packet[18:], 0x00000004) // VECTOR_ROOT_E131_DATA
+	copy(packet[22:38], cid[:])
+
+	// framing layer
+	binary.BigEndian.PutUint16(packet[38:], 0x7000|uint16(length-38))
+	binary.BigEndian.PutUint32(packet[40:], 0x00000002) // VECTOR_E131_DATA_PACKET
+	copy(packet[44:108], sourceName)
+	packet[108] = 100                           // priority
+	binary.BigEndian.PutUint16(packet[109:], 0) // synchronization address
+	packet[112] = 0                             // options
+	binary.BigEndian.PutUint16(packet[113:], uint16(universe))
+
+	// DMP layer
+	binary.BigEndian.PutUint16(packet[115:], 0x7000|uint16(length-115))
+	packet[117] = 0x02                                            // VECTOR_DMP_SET_PROPERTY
+	packet[118] = 0xA1                                            // address and data type
+	binary.BigEndian.PutUint16(packet[119:], 0)                   // first property address
+	binary.BigEndian.PutUint16(packet[121:], 1)                   // address increment
+	binary.BigEndian.PutUint16(packet[123:], uint16(slotCount+1)) // property count, includes the start code
+	packet[125] = 0                                               // DMX start code
+
+	return packet
+}
+
+// Send each universe
+func (this *E131Display) Render(colors []RGBA) {
+
+	this.sequence++
+
+	for index, span := range this.spans {
+		packet := this.packets[index]
+		packet[111] = this.sequence
+		span.fill(colors, packet[e131HeaderSize:])
+	}
+
+	this.send()
+}
+
+// Art-Net ArtDmx over UDP, broadcast when no address is configured
+type ArtNetDisplay struct {
+	*udpUniverseSender
+
+	// sequence number, incremented once per frame, 0 is never sent since it disables sequencing
+	sequence uint8
+}
+
+var _ Display = &ArtNetDisplay{}
+
+// size of the ArtDmx header before the DMX data
+const artNetHeaderSize = 18
+
+// Construct an ArtNetDisplay
+func NewArtNetDisplay(settings SettingsData) *ArtNetDisplay {
+
+	spans := splitUniverses(settings.LedCount, settings.NetworkStartUniverse, settings.NetworkStartChannel, settings.NetworkChannelsPerUniverse)
+
+	address := settings.NetworkAddress
+	if address == "" {
+		address = "255.255.255.255"
+	}
+	destination := func(universe int) string {
+		return withDefaultPort(address, artNetDefaultPort)
+	}
+
+	display := &ArtNetDisplay{
+		udpUniverseSender: newUdpUniverseSender(spans, destination),
+	}
+
+	for index, span := range spans {
+		display.packets[index] = newArtDmxPacket(span.universe, span.slotCount())
+	}
+
+	return display
+}
+
+// Build an ArtDmx packet with every header filled in except the sequence number
+func newArtDmxPacket(universe int, slotCount int) []byte {
+
+	// data length has to be even
+	if slotCount%2 == 1 {
+		slotCount++
+	}
+
+	packet := make([]byte, artNetHeaderSize+slotCount)
+
+	copy(packet[0:8], "Art-Net\x00")
+	binary.LittleEndian.PutUint16(packet[8:], 0x5000) // OpDmx
+	binary.BigEndian.PutUint16(packet[10:], 14)       // protocol version
+	packet[12] = 0                                    // sequence
+	packet[13] = 0                                    // physical port
+	packet[14] = uint8(universe)                      // sub-net and universe
+	packet[15] = uint8(universe>>8) & 0x7F            // net
+	binary.BigEndian.PutUint16(packet[16:], uint16(slotCount))
+
+	return packet
+}
+
+// Send each universe
+func (this *ArtNetDisplay) Render(colors []RGBA) {
+
+	this.sequence++
+	if this.sequence == 0 {
+		this.sequence = 1
+	}
+
+	for index, span := range this.spans {
+		packet := this.packets[index]
+		packet[12] = this.sequence
+		span.fill(colors, packet[artNetHeaderSize:])
+	}
+
+	this.send()
+}
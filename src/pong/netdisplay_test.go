@@ -0,0 +1,240 @@
+package pong
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// A universe of DMX data decoded by a loopback receiver
+type receivedUniverse struct {
+	universe int
+	sequence uint8
+	data     []byte
+}
+
+// Listen for UDP packets on a random loopback port
+func newUdpReceiver(t *testing.T) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+// Receive count packets and decode each of them
+func receiveUniverses(conn *net.UDPConn, count int, decode func([]byte, *testing.T) receivedUniverse, t *testing.T) (universes []receivedUniverse) {
+	buffer := make([]byte, 1024)
+	for len(universes) < count {
+		n, _, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		universes = append(universes, decode(append([]byte(nil), buffer[:n]...), t))
+	}
+	return
+}
+
+// Decode an E1.31 data packet, checking every header field
+func decodeE131(packet []byte, t *testing.T) receivedUniverse {
+	if len(packet) < e131HeaderSize {
+		t.Fatal("E1.31 packet too short", len(packet))
+	}
+
+	length := len(packet)
+	checks := []struct {
+		name             string
+		actual, expected int
+	}{
+		{"preamble", int(binary.BigEndian.Uint16(packet[0:])), 0x10},
+		{"root flags and length", int(binary.BigEndian.Uint16(packet[16:])), 0x7000 | (length - 16)},
+		{"root vector", int(binary.BigEndian.Uint32(packet[18:])), 4},
+		{"framing flags and length", int(binary.BigEndian.Uint16(packet[38:])), 0x7000 | (length - 38)},
+		{"framing vector", int(binary.BigEndian.Uint32(packet[40:])), 2},
+		{"priority", int(packet[108]), 100},
+		{"dmp flags and length", int(binary.BigEndian.Uint16(packet[115:])), 0x7000 | (length - 115)},
+		{"dmp vector", int(packet[117]), 2},
+		{"address type", int(packet[118]), 0xA1},
+		{"address increment", int(binary.BigEndian.Uint16(packet[121:])), 1},
+		{"property count", int(binary.BigEndian.Uint16(packet[123:])), length - e131HeaderSize + 1},
+		{"start code", int(packet[125]), 0},
+	}
+	for _, check := range checks {
+		Assert(check.actual, check.expected, "E1.31 "+check.name, t)
+	}
+	if !bytes.Equal(packet[4:16], []byte("ASC-E1.17\x00\x00\x00")) {
+		t.Fatal("Bad ACN packet identifier")
+	}
+
+	return receivedUniverse{
+		universe: int(binary.BigEndian.Uint16(packet[113:])),
+		sequence: packet[111],
+		data:     packet[e131HeaderSize:],
+	}
+}
+
+// Decode an ArtDmx packet, checking every header field
+func decodeArtDmx(packet []byte, t *testing.T) receivedUniverse {
+	if len(packet) < artNetHeaderSize || !bytes.Equal(packet[0:8], []byte("Art-Net\x00")) {
+		t.Fatal("Not an Art-Net packet")
+	}
+	Assert(int(binary.LittleEndian.Uint16(packet[8:])), 0x5000, "Art-Net opcode", t)
+	Assert(int(binary.BigEndian.Uint16(packet[10:])), 14, "Art-Net protocol version", t)
+
+	length := int(binary.BigEndian.Uint16(packet[16:]))
+	Assert(length, len(packet)-artNetHeaderSize, "Art-Net length", t)
+	Assert(length%2, 0, "Art-Net length must be even", t)
+
+	return receivedUniverse{
+		universe: int(packet[15])<<8 | int(packet[14]),
+		sequence: packet[12],
+		data:     packet[artNetHeaderSize:],
+	}
+}
+
+// colors that are easy to check, led i is {i, i+1, i+2}
+func netTestColors(count int) []RGBA {
+	colors := make([]RGBA, count)
+	for index := range colors {
+		colors[index] = RGBA{uint8(index), uint8(index + 1), uint8(index + 2), 255}
+	}
+	return colors
+}
+
+// Check that data holds the leds starting at firstLed, beginning at startChannel
+func assertUniverseData(data []byte, colors []RGBA, firstLed, ledCount, startChannel int, t *testing.T) {
+	for index := 0; index < ledCount; index++ {
+		color := colors[firstLed+index]
+		channel := startChannel + index*3
+		if data[channel] != color.R || data[channel+1] != color.G || data[channel+2] != color.B {
+			t.Fatal("Wrong color for led", firstLed+index, "at channel", channel)
+		}
+	}
+}
+
+// Long strips are split over universes without splitting a led
+func Test_NetDisplay_SplitUniverses(t *testing.T) {
+	spans := splitUniverses(400, 1, 0, 510)
+	Assert(len(spans), 3, "Universe count", t)
+	Assert(spans[0].ledCount, 170, "First universe leds", t)
+	Assert(spans[2].universe, 3, "Last universe", t)
+	Assert(spans[2].firstLed, 340, "Last universe first led", t)
+	Assert(spans[2].ledCount, 60, "Last universe leds", t)
+
+	spans = splitUniverses(100, 0, 9, 90)
+	Assert(len(spans), 4, "Offset universe count", t)
+	Assert(spans[0].ledCount, 27, "Offset first universe leds", t)
+	Assert(spans[0].slotCount(), 90, "Offset first universe slots", t)
+	Assert(spans[1].startChannel, 0, "Only the first universe is offset", t)
+}
+
+func Test_NetDisplay_E131(t *testing.T) {
+	receiver := newUdpReceiver(t)
+	defer receiver.Close()
+
+	colors := netTestColors(200)
+	display := NewE131Display(SettingsData{
+		LedCount:             len(colors),
+		NetworkAddress:       receiver.LocalAddr().String(),
+		NetworkStartUniverse: 5,
+	})
+
+	display.Render(colors)
+	universes := receiveUniverses(receiver, 2, decodeE131, t)
+
+	Assert(universes[0].universe, 5, "First universe", t)
+	Assert(universes[1].universe, 6, "Second universe", t)
+	Assert(int(universes[0].sequence), 1, "Sequence", t)
+	Assert(len(universes[0].data), 510, "First universe slots", t)
+	Assert(len(universes[1].data), 90, "Second universe slots", t)
+	assertUniverseData(universes[0].data, colors, 0, 170, 0, t)
+	assertUniverseData(universes[1].data, colors, 170, 30, 0, t)
+}
+
+func Test_NetDisplay_ArtNet(t *testing.T) {
+	receiver := newUdpReceiver(t)
+	defer receiver.Close()
+
+	colors := netTestColors(3)
+	display := NewArtNetDisplay(SettingsData{
+		LedCount:             len(colors),
+		NetworkAddress:       receiver.LocalAddr().String(),
+		NetworkStartUniverse: 0x123,
+		NetworkStartChannel:  1,
+	})
+
+	display.Render(colors)
+	display.Render(colors)
+	universes := receiveUniverses(receiver, 2, decodeArtDmx, t)
+
+	Assert(universes[0].universe, 0x123, "Universe", t)
+	Assert(int(universes[1].sequence), 2, "Sequence", t)
+	Assert(len(universes[0].data), 10, "Slots padded to even", t)
+	assertUniverseData(universes[0].data, colors, 0, 3, 1, t)
+}
+
+// A frame shorter than LedCount fills the leds it has and sends the rest black
+func Test_NetDisplay_ShortFrame(t *testing.T) {
+	receiver := newUdpReceiver(t)
+	defer receiver.Close()
+
+	colors := netTestColors(200)
+	display := NewE131Display(SettingsData{LedCount: len(colors), NetworkAddress: receiver.LocalAddr().String()})
+
+	display.Render(colors)
+	display.Render(colors[:180])
+	universes := receiveUniverses(receiver, 4, decodeE131, t)
+
+	assertUniverseData(universes[2].data, colors, 0, 170, 0, t)
+	assertUniverseData(universes[3].data, colors, 170, 10, 0, t)
+	assertUniverseData(universes[3].data[30:], make([]RGBA, 20), 0, 20, 0, t)
+
+	artNet := NewArtNetDisplay(SettingsData{LedCount: 3, NetworkAddress: receiver.LocalAddr().String()})
+	artNet.Render(colors[:1])
+	universes = receiveUniverses(receiver, 1, decodeArtDmx, t)
+
+	assertUniverseData(universes[0].data, colors, 0, 1, 0, t)
+	assertUniverseData(universes[0].data[3:], make([]RGBA, 2), 0, 2, 0, t)
+}
+
+func Test_NetDisplay_Opc(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	colors := netTestColors(4)
+	display := NewOpcDisplay(SettingsData{
+		LedCount:       len(colors),
+		NetworkAddress: listener.Addr().String(),
+		OpcChannel:     2,
+	})
+
+	display.Render(colors)
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		t.Fatal(err)
+	}
+	Assert(int(header[0]), 2, "OPC channel", t)
+	Assert(int(header[1]), 0, "OPC command", t)
+	Assert(int(binary.BigEndian.Uint16(header[2:])), 12, "OPC length", t)
+
+	data := make([]byte, 12)
+	if _, err := io.ReadFull(conn, data); err != nil {
+		t.Fatal(err)
+	}
+	assertUniverseData(data, colors, 0, 4, 0, t)
+}
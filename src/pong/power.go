@@ -0,0 +1,204 @@
+package pong
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Display decorator that dims frames to a brightness setting and to stay under a current budget
+type PowerLimiter struct {
+	display Display
+
+	// most current allowed in milliamps, 0 for no limit
+	maxMilliamps float64
+
+	// current of one fully lit channel, and of a dark led, in milliamps
+	milliampsPerChannel, idleMilliampsPerLed float64
+
+	// brightness during the day and during the night
+	brightness, nightBrightness float64
+
+	// hours of the day night mode starts and ends, night mode is off when they are equal
+	nightStartHour, nightEndHour int
+
+	// gamma correction applied by the chipset, the current of a channel follows the corrected value
+	gamma float64
+
+	// fraction of the time a channel is on for each color value
+	dutyLookup [256]float64
+
+	// used to check the time of day, replaced in tests
+	now func() time.Time
+
+	lock sync.Mutex
+
+	// estimates of the last frame before and after dimming
+	requestedMilliamps, limitedMilliamps float64
+
+	// how much the last frame was dimmed, 1 is not at all
+	scale float64
+
+	// dimmed frame
+	buffer []RGBA
+}
+
+var _ StatusDisplay = &PowerLimiter{}
+var _ CalibratedDisplay = &PowerLimiter{}
+var _ DisplayWrapper = &PowerLimiter{}
+
+// Wrap display with a PowerLimiter configured from settings
+func NewPowerLimiter(display Display, settings SettingsData) *PowerLimiter {
+
+	limiter := &PowerLimiter{
+		display:             display,
+		maxMilliamps:        settings.MaxMilliamps,
+		milliampsPerChannel: settings.MilliampsPerChannel,
+		idleMilliampsPerLed: settings.IdleMilliampsPerLed,
+		brightness:          settings.Brightness,
+		nightBrightness:     settings.NightBrightness,
+		nightStartHour:      settings.NightStartHour,
+		nightEndHour:        settings.NightEndHour,
+		now:                 time.Now,
+		scale:               1.0,
+	}
+
+	if limiter.milliampsPerChannel <= 0 {
+		limiter.milliampsPerChannel = 20
+	}
+	if limiter.brightness <= 0 || 1 < limiter.brightness {
+		limiter.brightness = 1
+	}
+
+	limiter.setGamma(settings.GammaExponent)
+
+	return limiter
+}
+
+// Rebuild the duty lookup for the gamma exponent the chipset uses
+func (this *PowerLimiter) setGamma(exponent float64) {
+
+	if exponent <= 0 {
+		exponent = defaultGammaExponent
+	}
+
+	this.gamma = exponent
+	for index := range this.dutyLookup {
+		this.dutyLookup[index] = math.Pow(float64(index)/255.0, exponent)
+	}
+}
+
+// Follow a change of the gamma exponent
+func (this *PowerLimiter) Calibrate(settings SettingsData) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.setGamma(settings.GammaExponent)
+}
+
+// The limited display
+func (this *PowerLimiter) WrappedDisplays() []Display {
+	return []Display{this.display}
+}
+
+// Brightness for the current time of day
+func (this *PowerLimiter) currentBrightness() float64 {
+
+	if this.nightStartHour == this.nightEndHour {
+		return this.brightness
+	}
+
+	hour := this.now().Hour()
+
+	var night bool
+	if this.nightStartHour < this.nightEndHour {
+		night = this.nightStartHour <= hour && hour < this.nightEndHour
+	} else {
+		night = hour >= this.nightStartHour || hour < this.nightEndHour // schedule wraps past midnight
+	}
+
+	if night {
+		return this.nightBrightness
+	}
+	return this.brightness
+}
+
+// Estimate the current in milliamps of showing colors, as sum of idle current and the current of each lit channel
+func (this *PowerLimiter) EstimateMilliamps(colors []RGBA) float64 {
+
+	var duty float64
+	for _, color := range colors {
+		duty += this.dutyLookup[color.R] + this.dutyLookup[color.G] + this.dutyLookup[color.B]
+	}
+
+	return float64(len(colors))*this.idleMilliampsPerLed + duty*this.milliampsPerChannel
+}
+
+// Dim colors when needed and render them to the wrapped display
+func (this *PowerLimiter) Render(colors []RGBA) {
+
+	this.lock.Lock()
+
+	idle := float64(len(colors)) * this.idleMilliampsPerLed
+	requested := this.EstimateMilliamps(colors)
+
+	// scaling every color value by brightness scales the lit current by brightness ^ gamma
+	brightness := this.currentBrightness()
+	limited := idle + (requested-idle)*math.Pow(brightness, this.gamma)
+
+	if this.maxMilliamps > 0 && limited > this.maxMilliamps {
+		// idle current alone is over the budget when nothing is lit, there's nothing left to dim
+		if requested <= idle {
+			brightness = 0
+		} else {
+			allowed := math.Max(this.maxMilliamps-idle, 0)
+			brightness = math.Pow(allowed/(requested-idle), 1.0/this.gamma)
+		}
+		limited = idle + (requested-idle)*math.Pow(brightness, this.gamma)
+	}
+
+	if len(this.buffer) != len(colors) {
+		this.buffer = make([]RGBA, len(colors))
+	}
+
+	if brightness >= 1 {
+		copy(this.buffer, colors)
+	} else {
+		for index, color := range colors {
+			this.buffer[index] = RGBA{
+				uint8(float64(color.R) * brightness),
+				uint8(float64(color.G) * brightness),
+				uint8(float64(color.B) * brightness),
+				color.A,
+			}
+		}
+	}
+
+	this.requestedMilliamps, this.limitedMilliamps, this.scale = requested, limited, brightness
+	this.lock.Unlock()
+
+	this.display.Render(this.buffer)
+}
+
+// Estimated current of the last frame before and after it was dimmed, in milliamps
+func (this *PowerLimiter) EstimatedMilliamps() (requested, limited float64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	return this.requestedMilliamps, this.limitedMilliamps
+}
+
+// Brightness the last frame was scaled by, 1 when it wasn't dimmed
+func (this *PowerLimiter) Scale() float64 {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	return this.scale
+}
+
+// Forward the status to the wrapped display
+func (this *PowerLimiter) SetStatus(status string) {
+	if statusDisplay, ok := this.display.(StatusDisplay); ok {
+		statusDisplay.SetStatus(status)
+	}
+}
@@ -0,0 +1,80 @@
+package pong
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Frame of count fully white leds
+func whiteFrame(count int) []RGBA {
+	colors := make([]RGBA, count)
+	for index := range colors {
+		colors[index] = RGBA{255, 255, 255, 255}
+	}
+	return colors
+}
+
+// Fully white leds draw 3 channels worth of current plus idle current
+func Test_PowerLimiter_Estimate(t *testing.T) {
+	limiter := NewPowerLimiter(&collectingDisplay{}, SettingsData{MilliampsPerChannel: 20, IdleMilliampsPerLed: 1})
+
+	Assert(int(limiter.EstimateMilliamps(whiteFrame(10))), 610, "White estimate", t)
+	Assert(int(limiter.EstimateMilliamps(make([]RGBA, 10))), 10, "Black estimate", t)
+}
+
+// A frame over the budget is dimmed until its estimate fits, one under it is untouched
+func Test_PowerLimiter_Limit(t *testing.T) {
+	display := &collectingDisplay{}
+	limiter := NewPowerLimiter(display, SettingsData{MaxMilliamps: 310, MilliampsPerChannel: 20, IdleMilliampsPerLed: 1})
+
+	limiter.Render(whiteFrame(10))
+	requested, limited := limiter.EstimatedMilliamps()
+	Assert(int(requested), 610, "Requested current", t)
+	if math.Abs(limited-310) > 0.01 {
+		t.Fatal("Limited estimate should be the budget, was", limited)
+	}
+	if actual := limiter.EstimateMilliamps(display.frames[0]); actual > 310 {
+		t.Fatal("Dimmed frame still draws", actual)
+	}
+	if limiter.Scale() >= 1 {
+		t.Fatal("Frame should have been dimmed")
+	}
+
+	limiter.Render([]RGBA{{255, 0, 0, 255}})
+	Assert(int(display.frames[1][0].R), 255, "Frame under budget is untouched", t)
+	Assert(int(limiter.Scale()*100), 100, "Scale under budget", t)
+}
+
+// Idle current alone over the budget turns a black frame off instead of scaling by 0/0
+func Test_PowerLimiter_IdleOverBudget(t *testing.T) {
+	display := &collectingDisplay{}
+	limiter := NewPowerLimiter(display, SettingsData{MaxMilliamps: 5, MilliampsPerChannel: 20, IdleMilliampsPerLed: 1})
+
+	limiter.Render(make([]RGBA, 10))
+	if math.IsNaN(limiter.Scale()) {
+		t.Fatal("Scale of a black frame is NaN")
+	}
+	Assert(int(limiter.Scale()*100), 0, "Scale of a black frame", t)
+	_, limited := limiter.EstimatedMilliamps()
+	Assert(int(limited), 10, "Limited estimate is the idle current", t)
+	Assert(int(display.frames[0][0].R), 0, "Black frame stays black", t)
+
+	limiter.Render(whiteFrame(10))
+	Assert(int(display.frames[1][0].R), 0, "Lit frame with no budget left", t)
+}
+
+// Night brightness applies between the night hours, including schedules that wrap past midnight
+func Test_PowerLimiter_NightMode(t *testing.T) {
+	display := &collectingDisplay{}
+	limiter := NewPowerLimiter(display, SettingsData{Brightness: 1, NightBrightness: 0.5, NightStartHour: 22, NightEndHour: 7})
+
+	for _, check := range []struct {
+		hour     int
+		expected int
+	}{{21, 200}, {22, 100}, {3, 100}, {7, 200}} {
+		limiter.now = func() time.Time { return time.Date(2020, 1, 1, check.hour, 0, 0, 0, time.Local) }
+		limiter.Render([]RGBA{{200, 0, 0, 255}})
+		Assert(int(display.frames[len(display.frames)-1][0].R), check.expected, "Red at hour", t)
+	}
+}
@@ -0,0 +1,65 @@
+package pong
+
+import (
+	"math"
+)
+
+// Seedable random numbers whose whole state is one number, so a game can be reproduced and saved with it.
+// Uses splitmix64, the same seed gives the same numbers on every platform
+type Random struct {
+	state uint64
+}
+
+// Construct a Random starting from seed
+func NewRandom(seed uint64) *Random {
+	return &Random{state: seed}
+}
+
+// State that continues the sequence when passed to SetState
+func (this *Random) State() uint64 {
+	return this.state
+}
+
+// Continue the sequence from a State
+func (this *Random) SetState(state uint64) {
+	this.state = state
+}
+
+// Next 64 random bits
+func (this *Random) Uint64() uint64 {
+	this.state += 0x9E3779B97F4A7C15
+	return mix64(this.state)
+}
+
+// Scramble the bits of value, used by splitmix64 and for random looking values without any state
+func mix64(value uint64) uint64 {
+	value = (value ^ value>>30) * 0xBF58476D1CE4E5B9
+	value = (value ^ value>>27) * 0x94D049BB133111EB
+	return value ^ value>>31
+}
+
+// Random number in [0, 1)
+func (this *Random) Float64() float64 {
+	return float64(this.Uint64()>>11) / (1 << 53)
+}
+
+// Random number in [0, n), n must be positive
+func (this *Random) Intn(n int) int {
+	if n <= 0 {
+		panic("Random.Intn called with n <= 0")
+	}
+	return int(this.Uint64() % uint64(n))
+}
+
+// Normally distributed number with mean 0 and standard deviation 1
+func (this *Random) NormFloat64() float64 {
+
+	// Box-Muller, 1 - Float64 keeps the log away from 0
+	radius := math.Sqrt(-2 * math.Log(1-this.Float64()))
+	return radius * math.Cos(2*math.Pi*this.Float64())
+}
+
+// Random looking number in [0, n) picked by seed and value without changing any state, for effects drawn every frame
+func Noise(seed uint64, value float64, n int) int {
+	return int(mix64(seed^math.Float64bits(value)) % uint64(n))
+}
@@ -0,0 +1,66 @@
+package pong
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_Random_SameSeedSameNumbers(t *testing.T) {
+	first, second := NewRandom(7), NewRandom(7)
+	for index := 0; index < 100; index++ {
+		if first.Uint64() != second.Uint64() {
+			t.Fatal("sequences differ at", index)
+		}
+	}
+
+	if NewRandom(7).Uint64() == NewRandom(8).Uint64() {
+		t.Fatal("different seeds gave the same number")
+	}
+}
+
+// splitmix64 from seed 1234567, as published with the reference implementation
+func Test_Random_KnownSequence(t *testing.T) {
+	random := NewRandom(1234567)
+	expected := []uint64{6457827717110365317, 3203168211198807973, 9817491932198370423}
+	for index, value := range expected {
+		if actual := random.Uint64(); actual != value {
+			t.Fatal("number", index, actual, "vs expected", value)
+		}
+	}
+}
+
+func Test_Random_StateContinues(t *testing.T) {
+	random := NewRandom(99)
+	random.Float64()
+	state := random.State()
+	next := random.Uint64()
+
+	restored := NewRandom(0)
+	restored.SetState(state)
+	if restored.Uint64() != next {
+		t.Fatal("restored state continued differently")
+	}
+}
+
+func Test_Random_Ranges(t *testing.T) {
+	random := NewRandom(3)
+	counts := make([]int, 5)
+	sum, squares := 0.0, 0.0
+	for index := 0; index < 10000; index++ {
+		value := random.Float64()
+		if value < 0 || value >= 1 {
+			t.Fatal("Float64 out of range", value)
+		}
+		counts[random.Intn(len(counts))]++
+
+		normal := random.NormFloat64()
+		sum += normal
+		squares += normal * normal
+	}
+
+	for value, count := range counts {
+		Assert(boolToInt(count > 1800 && count < 2200), 1, "Intn even spread of "+string(rune('0'+value)), t)
+	}
+	Assert(int(math.Abs(sum/10000)*10), 0, "NormFloat64 mean", t)
+	Assert(int(squares/10000*10+0.5), 10, "NormFloat64 variance", t)
+}
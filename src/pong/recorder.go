@@ -0,0 +1,190 @@
+package pong
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A frame captured by a RecordingDisplay
+type RecordedFrame struct {
+
+	// time since the recording started
+	Offset time.Duration
+
+	// colors of each led
+	Colors []RGBA
+}
+
+// Display decorator that passes frames through and keeps a copy of them while recording
+type RecordingDisplay struct {
+	display Display
+
+	lock sync.Mutex
+
+	recording bool
+	start     time.Time
+	frames    []RecordedFrame
+
+	// longest stretch of frames kept, older ones are dropped so a recording left running doesn't fill the memory
+	maxLength time.Duration
+}
+
+var _ StatusDisplay = &RecordingDisplay{}
+var _ DisplayWrapper = &RecordingDisplay{}
+
+// seconds of a recording kept when MaxRecordingSeconds isn't set
+const defaultMaxRecordingSeconds = 120.0
+
+// Wrap display so that its frames can be recorded
+func NewRecordingDisplay(display Display, settings SettingsData) *RecordingDisplay {
+
+	seconds := settings.MaxRecordingSeconds
+	if seconds <= 0 {
+		seconds = defaultMaxRecordingSeconds
+	}
+
+	return &RecordingDisplay{
+		display:   display,
+		maxLength: time.Duration(seconds * float64(time.Second)),
+	}
+}
+
+// Render to the wrapped display, keeping a timestamped copy of the frame while recording
+func (this *RecordingDisplay) Render(colors []RGBA) {
+
+	this.display.Render(colors)
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if !this.recording {
+		return
+	}
+
+	frame := RecordedFrame{
+		Offset: time.Since(this.start),
+		Colors: make([]RGBA, len(colors)),
+	}
+	copy(frame.Colors, colors)
+	this.frames = append(this.frames, frame)
+
+	// let go of the colors of the dropped frames right away, the rest move down when the slice grows
+	drop := 0
+	for frame.Offset-this.frames[drop].Offset > this.maxLength {
+		this.frames[drop] = RecordedFrame{}
+		drop++
+	}
+	this.frames = this.frames[drop:]
+}
+
+// The recorded display
+func (this *RecordingDisplay) WrappedDisplays() []Display {
+	return []Display{this.display}
+}
+
+// Forward the status to the wrapped display
+func (this *RecordingDisplay) SetStatus(status string) {
+	if statusDisplay, ok := this.display.(StatusDisplay); ok {
+		statusDisplay.SetStatus(status)
+	}
+}
+
+// Start a new recording, discarding anything that wasn't stopped
+func (this *RecordingDisplay) StartRecording() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.recording = true
+	this.start = time.Now()
+	this.frames = nil
+}
+
+// Stop recording and return the frames that were captured, timed from the first one that was kept
+func (this *RecordingDisplay) StopRecording() []RecordedFrame {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	frames := this.frames
+	this.recording = false
+	this.frames = nil
+
+	if len(frames) > 0 {
+		first := frames[0].Offset
+		for index := range frames {
+			frames[index].Offset -= first
+		}
+	}
+
+	return frames
+}
+
+// True while frames are being captured
+func (this *RecordingDisplay) IsRecording() bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	return this.recording
+}
+
+// Play frames into display, waiting between them so they keep their original timing
+func ReplayFrames(frames []RecordedFrame, display Display) {
+
+	start := time.Now()
+	for _, frame := range frames {
+		if wait := frame.Offset - time.Since(start); wait > 0 {
+			time.Sleep(wait)
+		}
+		display.Render(frame.Colors)
+	}
+}
+
+// Save frames in dir as name.gif, name.png and/or name.frames depending on formats (gif, apng, raw)
+func SaveRecording(dir, name string, frames []RecordedFrame, formats []string, options RecordingImageOptions) error {
+
+	if len(frames) == 0 {
+		return fmt.Errorf("recording %s has no frames", name)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, format := range formats {
+
+		var extension string
+		var write func(file *os.File) error
+
+		switch strings.ToLower(strings.TrimSpace(format)) {
+		case "gif":
+			extension = ".gif"
+			write = func(file *os.File) error { return WriteGif(file, frames, options) }
+		case "apng", "png":
+			extension = ".png"
+			write = func(file *os.File) error { return WriteApng(file, frames, options) }
+		case "raw":
+			extension = ".frames"
+			write = func(file *os.File) error { return WriteFrameLog(file, frames) }
+		default:
+			return fmt.Errorf("unknown recording format %q", format)
+		}
+
+		file, err := os.Create(filepath.Join(dir, name+extension))
+		if err != nil {
+			return err
+		}
+
+		err = write(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package pong
+
+import (
+	"bytes"
+	"image/gif"
+	"image/png"
+	"testing"
+	"time"
+)
+
+// Display that keeps every frame it is given
+type collectingDisplay struct {
+	frames [][]RGBA
+}
+
+func (display *collectingDisplay) Render(colors []RGBA) {
+	display.frames = append(display.frames, append([]RGBA(nil), colors...))
+}
+
+// Frames spaced 5ms apart, led 0 holds the frame index
+func recorderTestFrames(count int) []RecordedFrame {
+	frames := make([]RecordedFrame, count)
+	for index := range frames {
+		frames[index] = RecordedFrame{
+			Offset: time.Duration(index) * 5 * time.Millisecond,
+			Colors: []RGBA{{uint8(index), 0, 0, 255}, {0, 0, 255, 255}, {0, 255, 0, 255}},
+		}
+	}
+	return frames
+}
+
+// Only frames rendered while recording are kept, and they are passed through either way
+func Test_RecordingDisplay_StartStop(t *testing.T) {
+	wrapped := &collectingDisplay{}
+	recorder := NewRecordingDisplay(wrapped, SettingsData{})
+
+	buffer := []RGBA{{1, 0, 0, 255}}
+	recorder.Render(buffer)
+	recorder.StartRecording()
+	recorder.Render(buffer)
+	buffer[0].R = 2
+	recorder.Render(buffer)
+	frames := recorder.StopRecording()
+	recorder.Render(buffer)
+
+	Assert(len(wrapped.frames), 4, "Frames passed through", t)
+	Assert(len(frames), 2, "Frames recorded", t)
+	Assert(int(frames[0].Colors[0].R), 1, "Recorded frames are copies", t)
+	if recorder.IsRecording() {
+		t.Fatal("Still recording after stop")
+	}
+}
+
+// Only the last MaxRecordingSeconds of a long recording are kept, timed from the first frame kept
+func Test_RecordingDisplay_MaxLength(t *testing.T) {
+	recorder := NewRecordingDisplay(&collectingDisplay{}, SettingsData{MaxRecordingSeconds: 0.05})
+
+	recorder.StartRecording()
+	for index := 0; index < 40; index++ {
+		recorder.Render([]RGBA{{uint8(index), 0, 0, 255}})
+		time.Sleep(5 * time.Millisecond)
+	}
+	frames := recorder.StopRecording()
+
+	if len(frames) == 0 || len(frames) > 12 {
+		t.Fatal("Frames kept", len(frames))
+	}
+	Assert(int(frames[len(frames)-1].Colors[0].R), 39, "Last frame kept", t)
+	Assert(int(frames[0].Offset), 0, "First frame offset", t)
+	if length := frames[len(frames)-1].Offset; length > 50*time.Millisecond {
+		t.Fatal("Recording kept", length)
+	}
+}
+
+// The raw log must reproduce every frame exactly
+func Test_FrameLog_RoundTrip(t *testing.T) {
+	frames := recorderTestFrames(10)
+
+	var log bytes.Buffer
+	if err := WriteFrameLog(&log, frames); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadFrameLog(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Assert(len(read), len(frames), "Frame count", t)
+	for index := range frames {
+		if read[index].Offset != frames[index].Offset {
+			t.Fatal("Offset mismatch on frame", index)
+		}
+		for led := range frames[index].Colors {
+			if read[index].Colors[led] != frames[index].Colors[led] {
+				t.Fatal("Color mismatch on frame", index, "led", led)
+			}
+		}
+	}
+
+	replayed := &collectingDisplay{}
+	ReplayFrames(read, replayed)
+	Assert(len(replayed.frames), len(frames), "Replayed frames", t)
+}
+
+// GIF frames can't be shorter than 20ms, so every fourth 5ms frame is kept
+func Test_WriteGif(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteGif(&out, recorderTestFrames(10), RecordingImageOptions{Scale: 4, RowHeight: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	animation, err := gif.DecodeAll(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Assert(len(animation.Image), 3, "GIF frames", t)
+	Assert(animation.Delay[0], 2, "GIF delay", t)
+	Assert(animation.Image[0].Bounds().Dx(), 12, "GIF width", t)
+	Assert(animation.Image[0].Bounds().Dy(), 2, "GIF height", t)
+
+	_, _, blue, _ := animation.Image[1].At(5, 1).RGBA()
+	Assert(int(blue>>8), 255, "Second led is blue", t)
+}
+
+// An APNG is still a valid png showing the first frame
+func Test_WriteApng(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteApng(&out, recorderTestFrames(3), RecordingImageOptions{Scale: 2, RowHeight: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks, err := readPngChunks(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Assert(len(chunks["acTL"]), 1, "Animation control chunks", t)
+	Assert(len(chunks["fcTL"]), 3, "Frame control chunks", t)
+
+	first, err := png.Decode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Assert(first.Bounds().Dx(), 6, "APNG width", t)
+	_, green, _, _ := first.At(5, 2).RGBA()
+	Assert(int(green>>8), 255, "Third led is green", t)
+}
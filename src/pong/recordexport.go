@@ -0,0 +1,309 @@
+package pong
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"time"
+)
+
+// How recorded frames are turned into images
+type RecordingImageOptions struct {
+
+	// width in pixels of each led
+	Scale int
+
+	// height in pixels of each row of leds
+	RowHeight int
+
+	// leds in each row, 0 draws every led in a single row
+	Columns int
+}
+
+// Default image options, a 64 led strip becomes a 512x8 image
+var DefaultRecordingImageOptions = RecordingImageOptions{Scale: 8, RowHeight: 8}
+
+// Draw colors as rows of Scale x RowHeight blocks
+func (options RecordingImageOptions) render(colors []RGBA) *image.RGBA {
+
+	scale, rowHeight, columns := options.Scale, options.RowHeight, options.Columns
+	if scale < 1 {
+		scale = 1
+	}
+	if rowHeight < 1 {
+		rowHeight = 1
+	}
+
+	if columns < 1 || len(colors) < columns {
+		columns = len(colors)
+	}
+	rows := (len(colors) + columns - 1) / columns
+
+	frameImage := image.NewRGBA(image.Rect(0, 0, columns*scale, rows*rowHeight))
+	for index, ledColor := range colors {
+		x, y := index%columns, index/columns
+		block := image.Rect(x*scale, y*rowHeight, (x+1)*scale, (y+1)*rowHeight)
+		draw.Draw(frameImage, block, image.NewUniform(color.RGBA{ledColor.R, ledColor.G, ledColor.B, 255}), image.Point{}, draw.Src)
+	}
+
+	return frameImage
+}
+
+// Pick the frames to show and how long to show each one for, never faster than minDelay between frames
+func sampleFrames(frames []RecordedFrame, minDelay time.Duration) (picked []RecordedFrame, delays []time.Duration) {
+
+	for _, frame := range frames {
+		if len(picked) > 0 && frame.Offset-picked[len(picked)-1].Offset < minDelay {
+			continue
+		}
+		if len(picked) > 0 {
+			delays = append(delays, frame.Offset-picked[len(picked)-1].Offset)
+		}
+		picked = append(picked, frame)
+	}
+
+	// hold the last frame for the minimum time
+	if len(picked) > 0 {
+		delays = append(delays, minDelay)
+	}
+
+	return
+}
+
+// Exact palette when a frame has few enough colors, which is always the case for short strips
+func framePalette(colors []RGBA) color.Palette {
+
+	seen := make(map[RGBA]bool)
+	framePalette := color.Palette{}
+	for _, ledColor := range colors {
+		ledColor.A = 255
+		if !seen[ledColor] {
+			seen[ledColor] = true
+			framePalette = append(framePalette, color.RGBA(ledColor))
+		}
+		if len(framePalette) > 256 {
+			return palette.Plan9
+		}
+	}
+
+	return framePalette
+}
+
+// Write frames as an animated GIF, looping forever. GIF delays are in 1/100 s, so frames closer than 20ms are dropped
+func WriteGif(w io.Writer, frames []RecordedFrame, options RecordingImageOptions) error {
+
+	if len(frames) == 0 {
+		return errors.New("no frames to write")
+	}
+
+	picked, delays := sampleFrames(frames, 20*time.Millisecond)
+	animation := &gif.GIF{}
+
+	for index, frame := range picked {
+		fullColor := options.render(frame.Colors)
+
+		paletted := image.NewPaletted(fullColor.Bounds(), framePalette(frame.Colors))
+		draw.Draw(paletted, paletted.Bounds(), fullColor, image.Point{}, draw.Src)
+
+		animation.Image = append(animation.Image, paletted)
+		animation.Delay = append(animation.Delay, int((delays[index]+5*time.Millisecond)/(10*time.Millisecond)))
+	}
+
+	return gif.EncodeAll(w, animation)
+}
+
+// Write frames as an animated PNG (APNG), looping forever, delays are kept to the millisecond
+func WriteApng(w io.Writer, frames []RecordedFrame, options RecordingImageOptions) error {
+
+	if len(frames) == 0 {
+		return errors.New("no frames to write")
+	}
+
+	picked, delays := sampleFrames(frames, time.Millisecond)
+	out := bufio.NewWriter(w)
+	sequence := uint32(0)
+
+	if _, err := out.Write([]byte("\x89PNG\r\n\x1a\n")); err != nil {
+		return err
+	}
+
+	for index, frame := range picked {
+		frameImage := options.render(frame.Colors)
+
+		var encoded bytes.Buffer
+		if err := png.Encode(&encoded, frameImage); err != nil {
+			return err
+		}
+		chunks, err := readPngChunks(encoded.Bytes())
+		if err != nil {
+			return err
+		}
+
+		if index == 0 {
+			// every frame is the same size and opaque, so the first header describes them all
+			writePngChunk(out, "IHDR", chunks["IHDR"][0])
+
+			animationControl := make([]byte, 8)
+			binary.BigEndian.PutUint32(animationControl[0:], uint32(len(picked)))
+			binary.BigEndian.PutUint32(animationControl[4:], 0) // loop forever
+			writePngChunk(out, "acTL", animationControl)
+		}
+
+		bounds := frameImage.Bounds()
+		frameControl := make([]byte, 26)
+		binary.BigEndian.PutUint32(frameControl[0:], sequence)
+		binary.BigEndian.PutUint32(frameControl[4:], uint32(bounds.Dx()))
+		binary.BigEndian.PutUint32(frameControl[8:], uint32(bounds.Dy()))
+		binary.BigEndian.PutUint32(frameControl[12:], 0) // x offset
+		binary.BigEndian.PutUint32(frameControl[16:], 0) // y offset
+		binary.BigEndian.PutUint16(frameControl[20:], uint16(delays[index]/time.Millisecond))
+		binary.BigEndian.PutUint16(frameControl[22:], 1000) // delay is in milliseconds
+		frameControl[24] = 0                                // dispose op none
+		frameControl[25] = 0                                // blend op source
+		writePngChunk(out, "fcTL", frameControl)
+		sequence++
+
+		for _, data := range chunks["IDAT"] {
+			if index == 0 {
+				writePngChunk(out, "IDAT", data)
+			} else {
+				frameData := make([]byte, 4+len(data))
+				binary.BigEndian.PutUint32(frameData, sequence)
+				copy(frameData[4:], data)
+				writePngChunk(out, "fdAT", frameData)
+				sequence++
+			}
+		}
+	}
+
+	writePngChunk(out, "IEND", nil)
+	return out.Flush()
+}
+
+// Split an encoded png into its chunks, grouped by type
+func readPngChunks(data []byte) (map[string][][]byte, error) {
+
+	if len(data) < 8 {
+		return nil, errors.New("png too short")
+	}
+
+	chunks := make(map[string][][]byte)
+	for offset := 8; offset+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		chunkType := string(data[offset+4 : offset+8])
+		if offset+12+length > len(data) {
+			return nil, errors.New("truncated png chunk " + chunkType)
+		}
+
+		chunks[chunkType] = append(chunks[chunkType], data[offset+8:offset+8+length])
+		offset += 12 + length
+	}
+
+	return chunks, nil
+}
+
+// Write a png chunk with its length and crc
+func writePngChunk(out *bufio.Writer, chunkType string, data []byte) {
+
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	out.Write(length[:])
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunkType))
+	crc.Write(data)
+
+	out.WriteString(chunkType)
+	out.Write(data)
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	out.Write(sum[:])
+}
+
+// Identifies a raw frame log
+const frameLogMagic = "PONGFRM1"
+
+// Write frames losslessly so they can be replayed later. The format is
+//
+//	8 bytes   "PONGFRM1"
+//	uint32    number of leds in each frame
+//	repeated for each frame:
+//	  int64   nanoseconds since the recording started
+//	  3 bytes per led, R G B
+//
+// with every integer big endian.
+func WriteFrameLog(w io.Writer, frames []RecordedFrame) error {
+
+	ledCount := 0
+	if len(frames) > 0 {
+		ledCount = len(frames[0].Colors)
+	}
+
+	out := bufio.NewWriter(w)
+	out.WriteString(frameLogMagic)
+	binary.Write(out, binary.BigEndian, uint32(ledCount))
+
+	for _, frame := range frames {
+		if len(frame.Colors) != ledCount {
+			return errors.New("every frame in a log must have the same number of leds")
+		}
+
+		binary.Write(out, binary.BigEndian, int64(frame.Offset))
+		out.Write(encodeFrame(frame.Colors))
+	}
+
+	return out.Flush()
+}
+
+// Read frames written by WriteFrameLog
+func ReadFrameLog(r io.Reader) ([]RecordedFrame, error) {
+
+	in := bufio.NewReader(r)
+
+	magic := make([]byte, len(frameLogMagic))
+	if _, err := io.ReadFull(in, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != frameLogMagic {
+		return nil, errors.New("not a pongpi frame log")
+	}
+
+	var ledCount uint32
+	if err := binary.Read(in, binary.BigEndian, &ledCount); err != nil {
+		return nil, err
+	}
+
+	frames := []RecordedFrame{}
+	data := make([]byte, ledCount*3)
+	for {
+		var offset int64
+		if err := binary.Read(in, binary.BigEndian, &offset); err == io.EOF {
+			return frames, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if _, err := io.ReadFull(in, data); err != nil {
+			return nil, err
+		}
+
+		frame := RecordedFrame{
+			Offset: time.Duration(offset),
+			Colors: make([]RGBA, ledCount),
+		}
+		for index := range frame.Colors {
+			frame.Colors[index] = RGBA{data[index*3], data[index*3+1], data[index*3+2], 255}
+		}
+		frames = append(frames, frame)
+	}
+}
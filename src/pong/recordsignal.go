@@ -0,0 +1,14 @@
+// +build !windows
+
+package pong
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Send on c whenever the process receives SIGUSR1, used to start and stop a recording
+func NotifyRecordToggle(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR1)
+}
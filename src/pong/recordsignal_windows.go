@@ -0,0 +1,11 @@
+// +build windows
+
+package pong
+
+import (
+	"os"
+)
+
+// There is no SIGUSR1 on windows, recordings can only be started with flags
+func NotifyRecordToggle(c chan<- os.Signal) {
+}
@@ -0,0 +1,278 @@
+package pong
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A played match, replayed by running its button presses through the same scenes with random numbers from the same seed
+type GameReplay struct {
+
+	// when the match started
+	Played time.Time
+
+	// size of the field the match was played on
+	Width, Height int
+
+	// settings the match was played with
+	SimulationHz, LifeInSeconds, BounceVelocityIncrease float64
+	MatchBestOf                                         int
+
+	// two players on each side and the depth of the inner paddles
+	Doubles          bool
+	InnerPaddleDepth int
+
+	// average time between power-ups, 0 for none, and the ones that appear
+	PowerUpSeconds float64
+	PowerUps       string
+
+	// timing windows of the skill shots and their effects
+	Shots ShotSettings
+
+	// handicap of each side, Color isn't kept but PaddleColor is
+	LeftProfile, RightProfile PlayerProfile
+
+	// difficulty of the computer players, empty for people
+	LeftPlayerAI, RightPlayerAI string
+
+	// updates from the start of the match until its last point was lost
+	Steps int64
+
+	// seed and the button presses counted from the start of the match
+	Inputs InputLog
+}
+
+// Identifies a replay file, followed by the version of the format as a digit
+const gameReplayMagic, gameReplayVersion = "PONGRPL", 5
+
+// Write a replay. The format is
+//
+//	8 bytes   "PONGRPL5"
+//	int64     when the match started, nanoseconds since 1970
+//	uint16    width of the field
+//	uint16    height of the field
+//	float64   SimulationHz
+//	float64   LifeInSeconds
+//	float64   BounceVelocityIncrease
+//	uint16    MatchBestOf
+//	uint8     1 for doubles, 0 for singles
+//	uint16    InnerPaddleDepth
+//	float64   PowerUpSeconds
+//	float64   Shots, Smash, SmashSpeed, SmashAngle, Soft, SoftSpeed, SoftAngle, DoubleTap and FakeOut
+//	repeated for the left and right profile:
+//	  float64 Life, Paddle, Drain and SpeedUp
+//	  4 bytes PaddleColor, red, green, blue and alpha
+//	string    PowerUps, a uint8 length and that many bytes
+//	string    LeftPlayerAI, a uint8 length and that many bytes
+//	string    RightPlayerAI
+//	string    LeftProfile name
+//	string    RightProfile name
+//	uint64    seed of the random numbers
+//	int64     updates in the match
+//	uint32    number of presses
+//	repeated for each press:
+//	  int64   update the press happened in, the first is 0
+//	  int64   nanoseconds after the start of that update
+//	  uint8   button, 0 left, 1 right, 2 left inner or 3 right inner
+//	  uint8   event, 1 push or 2 release
+//
+// with every number big endian. Version 1 has no doubles and inner paddle depth, versions 1 and 2 have no power-ups
+// versions before 4 have no shots and versions before 5 no profiles.
+func WriteGameReplay(w io.Writer, replay *GameReplay) error {
+
+	names := []string{replay.PowerUps, replay.LeftPlayerAI, replay.RightPlayerAI, replay.LeftProfile.Name, replay.RightProfile.Name}
+	for _, name := range names {
+		if len(name) > 255 {
+			return errors.New("AI difficulty, power-ups and profile names of a replay are at most 255 bytes")
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	out.WriteString(gameReplayMagic)
+	out.WriteByte(byte('0' + gameReplayVersion))
+
+	binary.Write(out, binary.BigEndian, replay.Played.UnixNano())
+	binary.Write(out, binary.BigEndian, uint16(replay.Width))
+	binary.Write(out, binary.BigEndian, uint16(replay.Height))
+	binary.Write(out, binary.BigEndian, replay.SimulationHz)
+	binary.Write(out, binary.BigEndian, replay.LifeInSeconds)
+	binary.Write(out, binary.BigEndian, replay.BounceVelocityIncrease)
+	binary.Write(out, binary.BigEndian, uint16(replay.MatchBestOf))
+	if replay.Doubles {
+		out.WriteByte(1)
+	} else {
+		out.WriteByte(0)
+	}
+	binary.Write(out, binary.BigEndian, uint16(replay.InnerPaddleDepth))
+	binary.Write(out, binary.BigEndian, replay.PowerUpSeconds)
+	binary.Write(out, binary.BigEndian, replay.Shots)
+	for _, profile := range []PlayerProfile{replay.LeftProfile, replay.RightProfile} {
+		binary.Write(out, binary.BigEndian, replayProfile{profile.Life, profile.Paddle, profile.Drain, profile.SpeedUp, profile.PaddleColor})
+	}
+	for _, name := range names {
+		out.WriteByte(byte(len(name)))
+		out.WriteString(name)
+	}
+	binary.Write(out, binary.BigEndian, replay.Inputs.Seed)
+	binary.Write(out, binary.BigEndian, replay.Steps)
+
+	binary.Write(out, binary.BigEndian, uint32(len(replay.Inputs.Presses)))
+	for _, press := range replay.Inputs.Presses {
+		binary.Write(out, binary.BigEndian, press.Step)
+		binary.Write(out, binary.BigEndian, int64(press.Offset))
+		out.WriteByte(byte(press.Button))
+		out.WriteByte(byte(press.Event))
+	}
+
+	return out.Flush()
+}
+
+// Numbers of a PlayerProfile as they're written in a replay
+type replayProfile struct {
+	Life, Paddle, Drain, SpeedUp float64
+	Color                        RGBA
+}
+
+// Read a replay written by WriteGameReplay, its InputLog starts at step 0
+func ReadGameReplay(r io.Reader) (*GameReplay, error) {
+
+	in := bufio.NewReader(r)
+
+	magic := make([]byte, len(gameReplayMagic)+1)
+	if _, err := io.ReadFull(in, magic); err != nil {
+		return nil, err
+	}
+	version := int(magic[len(gameReplayMagic)]) - '0'
+	if string(magic[:len(gameReplayMagic)]) != gameReplayMagic || version < 1 || version > gameReplayVersion {
+		return nil, errors.New("not a pongpi replay")
+	}
+
+	var header struct {
+		Played                                              int64
+		Width, Height                                       uint16
+		SimulationHz, LifeInSeconds, BounceVelocityIncrease float64
+		MatchBestOf                                         uint16
+	}
+	if err := binary.Read(in, binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+
+	replay := &GameReplay{
+		Played:                 time.Unix(0, header.Played),
+		Width:                  int(header.Width),
+		Height:                 int(header.Height),
+		SimulationHz:           header.SimulationHz,
+		LifeInSeconds:          header.LifeInSeconds,
+		BounceVelocityIncrease: header.BounceVelocityIncrease,
+		MatchBestOf:            int(header.MatchBestOf),
+	}
+
+	names := []*string{&replay.LeftPlayerAI, &replay.RightPlayerAI}
+	if version >= 2 {
+		var doubles struct {
+			Doubles          uint8
+			InnerPaddleDepth uint16
+		}
+		if err := binary.Read(in, binary.BigEndian, &doubles); err != nil {
+			return nil, err
+		}
+		replay.Doubles, replay.InnerPaddleDepth = doubles.Doubles != 0, int(doubles.InnerPaddleDepth)
+	}
+	if version >= 3 {
+		if err := binary.Read(in, binary.BigEndian, &replay.PowerUpSeconds); err != nil {
+			return nil, err
+		}
+		names = append([]*string{&replay.PowerUps}, names...)
+	}
+	if version >= 4 {
+		if err := binary.Read(in, binary.BigEndian, &replay.Shots); err != nil {
+			return nil, err
+		}
+	}
+	if version >= 5 {
+		for _, profile := range []*PlayerProfile{&replay.LeftProfile, &replay.RightProfile} {
+			var read replayProfile
+			if err := binary.Read(in, binary.BigEndian, &read); err != nil {
+				return nil, err
+			}
+			profile.Life, profile.Paddle, profile.Drain, profile.SpeedUp, profile.PaddleColor = read.Life, read.Paddle, read.Drain, read.SpeedUp, read.Color
+		}
+		names = append(names, &replay.LeftProfile.Name, &replay.RightProfile.Name)
+	}
+
+	for _, name := range names {
+		length, err := in.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(in, data); err != nil {
+			return nil, err
+		}
+		*name = string(data)
+	}
+
+	var counts struct {
+		Seed    uint64
+		Steps   int64
+		Presses uint32
+	}
+	if err := binary.Read(in, binary.BigEndian, &counts); err != nil {
+		return nil, err
+	}
+	replay.Inputs.Seed, replay.Steps = counts.Seed, counts.Steps
+
+	for index := uint32(0); index < counts.Presses; index++ {
+		var press struct {
+			Step, Offset  int64
+			Button, Event uint8
+		}
+		if err := binary.Read(in, binary.BigEndian, &press); err != nil {
+			return nil, err
+		}
+		replay.Inputs.Presses = append(replay.Inputs.Presses, LoggedPress{
+			Step:   press.Step,
+			Offset: time.Duration(press.Offset),
+			Button: ButtonId(press.Button),
+			Event:  ButtonEvent(press.Event),
+		})
+	}
+
+	return replay, nil
+}
+
+// Save replay as name.replay in dir
+func SaveGameReplay(dir, name string, replay *GameReplay) error {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(filepath.Join(dir, name+".replay"))
+	if err != nil {
+		return err
+	}
+
+	if err := WriteGameReplay(file, replay); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Read a replay saved at path
+func LoadGameReplay(path string) (*GameReplay, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadGameReplay(file)
+}
@@ -0,0 +1,124 @@
+package pong
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func Test_GameReplay_WriteRead(t *testing.T) {
+	replay := &GameReplay{
+		Played:                 time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		Width:                  16,
+		Height:                 8,
+		SimulationHz:           240,
+		LifeInSeconds:          5,
+		BounceVelocityIncrease: 1.1,
+		MatchBestOf:            3,
+		Doubles:                true,
+		InnerPaddleDepth:       6,
+		PowerUpSeconds:         7.5,
+		PowerUps:               "life,multiball",
+		Shots:                  ShotSettings{Smash: 0.04, SmashSpeed: 1.25, Soft: 0.3, DoubleTap: 0.25, FakeOut: 0.15},
+		LeftProfile:            PlayerProfile{Name: "kid", Life: 8, Paddle: 2, Drain: 0.5, PaddleColor: RGBA{255, 128, 0, 255}},
+		RightPlayerAI:          "hard",
+		Steps:                  12345,
+		Inputs: InputLog{
+			Seed: 0xFEEDFACE12345678,
+			Presses: []LoggedPress{
+				{Step: 0, Offset: 0, Button: LeftButtonId, Event: ButtonPush},
+				{Step: 99, Offset: 1500 * time.Microsecond, Button: RightButtonId, Event: ButtonRelease},
+				{Step: 120, Offset: 0, Button: RightInnerButtonId, Event: ButtonPush},
+			},
+		},
+	}
+
+	var buffer bytes.Buffer
+	if err := WriteGameReplay(&buffer, replay); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadGameReplay(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !read.Played.Equal(replay.Played) {
+		t.Fatal("played", read.Played, "vs expected", replay.Played)
+	}
+	Assert(read.Width, 16, "width", t)
+	Assert(read.Height, 8, "height", t)
+	Assert(int(read.SimulationHz), 240, "simulation hz", t)
+	Assert(int(read.BounceVelocityIncrease*10+0.5), 11, "bounce increase", t)
+	Assert(read.MatchBestOf, 3, "best of", t)
+	Assert(boolToInt(read.Doubles), 1, "doubles", t)
+	Assert(read.InnerPaddleDepth, 6, "inner paddle depth", t)
+	Assert(int(read.PowerUpSeconds*10), 75, "power-up seconds", t)
+	if read.PowerUps != "life,multiball" {
+		t.Fatal("power-ups", read.PowerUps)
+	}
+	if read.Shots != replay.Shots {
+		t.Fatal("shots", read.Shots, "vs expected", replay.Shots)
+	}
+	if read.LeftProfile != replay.LeftProfile || read.RightProfile != (PlayerProfile{}) {
+		t.Fatal("profiles", read.LeftProfile, read.RightProfile)
+	}
+	if read.LeftPlayerAI != "" || read.RightPlayerAI != "hard" {
+		t.Fatal("AI", read.LeftPlayerAI, read.RightPlayerAI)
+	}
+	Assert(int(read.Steps), 12345, "steps", t)
+	if read.Inputs.Seed != replay.Inputs.Seed {
+		t.Fatal("seed", read.Inputs.Seed)
+	}
+	Assert(len(read.Inputs.Presses), 3, "presses", t)
+	if read.Inputs.Presses[1] != replay.Inputs.Presses[1] {
+		t.Fatal("press", read.Inputs.Presses[1], "vs expected", replay.Inputs.Presses[1])
+	}
+}
+
+// Replays written by earlier versions are read without the settings they didn't have
+func Test_GameReplay_ReadsEarlierVersions(t *testing.T) {
+	var buffer bytes.Buffer
+	WriteGameReplay(&buffer, &GameReplay{Width: 16, Height: 1, MatchBestOf: 1, Steps: 5, LeftPlayerAI: "easy"})
+	data := buffer.Bytes()
+
+	// where each part a version added starts: the doubles, power-up seconds, shots, profiles, the power-ups name, the AI names that
+	// every version has and the profile names, the names but the left AI are empty here
+	doubles := len(gameReplayMagic) + 1 + 8 + 2 + 2 + 3*8 + 2
+	powerUpSeconds := doubles + 3
+	shots := powerUpSeconds + 8
+	profiles := shots + 8*8
+	powerUps := profiles + 2*(4*8+4)
+	aiNames := powerUps + 1
+	profileNames := aiNames + 1 + len("easy") + 1
+	seed := profileNames + 2
+
+	// parts of the replay kept by each version
+	for version, parts := range map[byte][][2]int{
+		'1': {{0, doubles}, {aiNames, profileNames}, {seed, len(data)}},
+		'2': {{0, powerUpSeconds}, {aiNames, profileNames}, {seed, len(data)}},
+		'3': {{0, shots}, {powerUps, profileNames}, {seed, len(data)}},
+		'4': {{0, profiles}, {powerUps, profileNames}, {seed, len(data)}},
+	} {
+		var old []byte
+		for _, part := range parts {
+			old = append(old, data[part[0]:part[1]]...)
+		}
+		old[len(gameReplayMagic)] = version
+
+		read, err := ReadGameReplay(bytes.NewBuffer(old))
+		if err != nil {
+			t.Fatal(err)
+		}
+		Assert(boolToInt(read.Doubles), 0, "singles", t)
+		Assert(int(read.Steps), 5, "steps", t)
+		if read.LeftPlayerAI != "easy" || read.PowerUps != "" {
+			t.Fatal("names", read.LeftPlayerAI, read.PowerUps)
+		}
+	}
+}
+
+func Test_GameReplay_NotAReplay(t *testing.T) {
+	if _, err := ReadGameReplay(bytes.NewBufferString("PONGFRM1 not a replay")); err == nil {
+		t.Fatal("frame log read as a replay")
+	}
+}
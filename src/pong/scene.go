@@ -0,0 +1,406 @@
+package pong
+
+import (
+	"log"
+	"time"
+)
+
+// Names returned by Scene.Update and Scene.Button that aren't scenes
+const (
+	StayInScene = ""     // keep running the current scene
+	QuitScenes  = "quit" // stop the SceneRunner
+)
+
+// A phase of the application, like the intro or a round of play
+type Scene interface {
+
+	// Set up the scene as it becomes the current one
+	Enter()
+
+	// Move the scene forward by dt seconds, returns the name of the next scene or StayInScene
+	Update(dt float64) (next string)
+
+	// Handle a button push or release, called before the update it happened in, press.Time is at or after SceneRunner.Time
+	Button(press ButtonPress) (next string)
+
+	// Field the scene draws, rendered after every frame
+	Field() *GameField
+
+	// Clean up as another scene takes over
+	Exit()
+}
+
+// State of a SceneRunner and its current scene, the scene must be a Snapshotter
+type SceneSnapshot struct {
+	current string
+	steps   int64
+	held    [ButtonCount]bool
+	state   interface{}
+}
+
+// Scene hooks that do nothing, embedded by scenes that don't need all of them
+type SceneBase struct {
+}
+
+// Nothing to set up
+func (this *SceneBase) Enter() {
+}
+
+// Buttons are ignored
+func (this *SceneBase) Button(press ButtonPress) string {
+	return StayInScene
+}
+
+// Nothing to clean up
+func (this *SceneBase) Exit() {
+}
+
+// longest time the scenes catch up on after a stall, older time is skipped
+const maxSceneCatchUp = 250 * time.Millisecond
+
+// Runs the current Scene in a single frame loop and switches between scenes as they ask for it.
+// Scenes are updated in fixed steps independent of the frame rate, so the same button presses at the same steps play the same game
+type SceneRunner struct {
+	display Display
+
+	// buttons passed to the scenes, nil for none
+	input InputSource
+
+	// time between frames and between updates of the scenes
+	frameTime, stepTime time.Duration
+
+	scenes map[string]Scene
+
+	// cross fade time in seconds for each change of scene, keyed by from and to, an empty from matches every scene
+	fades map[[2]string]float64
+
+	current string
+	scene   Scene
+
+	// updates since Start and the time the scenes have been moved forward to
+	steps    int64
+	stepsEnd time.Time
+
+	// buttons held after the presses passed to the scenes so far
+	held [ButtonCount]bool
+
+	// log the button presses are recorded in, nil while not recording
+	inputLog *InputLog
+
+	// last frame of the previous scene while fading from it
+	fadeFrom                []RGBA
+	fadeTime, fadeRemaining float64
+
+	// frame of the current scene and the blended frame while fading
+	captured, blended []RGBA
+}
+
+// Construct a SceneRunner drawing to display at one frame every frameTime and updating the scenes every stepTime
+func NewSceneRunner(display Display, input InputSource, frameTime, stepTime time.Duration) *SceneRunner {
+	return &SceneRunner{
+		display:   display,
+		input:     input,
+		frameTime: frameTime,
+		stepTime:  stepTime,
+		scenes:    make(map[string]Scene),
+		fades:     make(map[[2]string]float64),
+	}
+}
+
+// Register a scene under name
+func (this *SceneRunner) Add(name string, scene Scene) {
+	this.scenes[name] = scene
+}
+
+// Cross fade for seconds when changing from one scene to another, an empty from fades from every scene
+func (this *SceneRunner) SetFade(from, to string, seconds float64) {
+	this.fades[[2]string{from, to}] = seconds
+}
+
+// Name of the current scene
+func (this *SceneRunner) Current() string {
+	return this.current
+}
+
+// Field of the current scene
+func (this *SceneRunner) Field() *GameField {
+	return this.scene.Field()
+}
+
+// Number of updates since Start
+func (this *SceneRunner) Steps() int64 {
+	return this.steps
+}
+
+// Time the scenes have been updated to, the start of the next step
+func (this *SceneRunner) Time() time.Time {
+	return this.stepsEnd
+}
+
+// True while button is held, going by the presses passed to the scenes so it's the same when they are replayed
+func (this *SceneRunner) Held(button ButtonId) bool {
+	return this.held[button]
+}
+
+// Record the button presses passed to the scenes from now on in inputLog, nil stops recording.
+// Buttons held already are logged as pushed at the start
+func (this *SceneRunner) RecordInputs(inputLog *InputLog) {
+	if inputLog != nil {
+		inputLog.Start = this.steps
+		for button, held := range this.held {
+			if held {
+				inputLog.Add(this.steps, 0, ButtonPress{Button: ButtonId(button), Event: ButtonPush, Time: this.stepsEnd})
+			}
+		}
+	}
+	this.inputLog = inputLog
+}
+
+// Enter the named scene at time now
+func (this *SceneRunner) Start(name string, now time.Time) {
+	this.steps, this.stepsEnd = 0, now
+	this.change(name)
+}
+
+// Save the current scene and its state
+func (this *SceneRunner) Snapshot() *SceneSnapshot {
+
+	snapshotter, ok := this.scene.(Snapshotter)
+	if !ok {
+		log.Fatal("Scene ", this.current, " can't be saved in a snapshot")
+	}
+
+	return &SceneSnapshot{
+		current: this.current,
+		steps:   this.steps,
+		held:    this.held,
+		state:   snapshotter.Snapshot(),
+	}
+}
+
+// Go back to the scene and step of a Snapshot without exiting or entering scenes, the snapshot can come from another runner with the same scene names
+func (this *SceneRunner) Restore(snapshot *SceneSnapshot) {
+
+	scene, ok := this.scenes[snapshot.current].(Snapshotter)
+	if !ok {
+		log.Fatal("Scene ", snapshot.current, " can't be restored from a snapshot")
+	}
+	scene.Restore(snapshot.state)
+
+	this.current, this.scene = snapshot.current, scene.(Scene)
+	this.steps, this.held = snapshot.steps, snapshot.held
+	this.fadeRemaining = 0
+}
+
+// Run frames until a scene quits, starting with the named scene
+func (this *SceneRunner) Run(name string) {
+
+	this.Start(name, time.Now())
+
+	ticks := time.NewTicker(this.frameTime)
+	defer ticks.Stop()
+
+	for now := range ticks.C {
+		if !this.Step(now) {
+			return
+		}
+	}
+}
+
+// Move the scenes forward to now and render a frame, returns false once a scene quits
+func (this *SceneRunner) Step(now time.Time) bool {
+
+	if now.Sub(this.stepsEnd) > maxSceneCatchUp {
+		this.stepsEnd = now.Add(-maxSceneCatchUp)
+	}
+
+	// each button event is handled just before the step it happened in, so a tap between two frames still counts
+	if this.input != nil {
+		for _, press := range receiveButtonPresses(this.input.Events(), now) {
+			if !this.advanceTo(press.Time) || !this.Press(press) {
+				return false
+			}
+		}
+	}
+
+	if !this.advanceTo(now) {
+		return false
+	}
+
+	// draw the scene between its last two steps, the part of a step that has passed since the last one
+	this.render(float64(now.Sub(this.stepsEnd)) / float64(this.stepTime))
+	return true
+}
+
+// Run the steps that end by until, returns false once a scene quits
+func (this *SceneRunner) advanceTo(until time.Time) bool {
+	for !this.stepsEnd.Add(this.stepTime).After(until) {
+		if !this.Advance(1) {
+			return false
+		}
+	}
+	return true
+}
+
+// Update the current scene count steps, returns false once a scene quits
+func (this *SceneRunner) Advance(count int) bool {
+
+	dt := this.stepTime.Seconds()
+	for ; count > 0; count-- {
+		this.steps++
+		this.stepsEnd = this.stepsEnd.Add(this.stepTime)
+		this.fadeRemaining -= dt
+
+		if !this.follow(this.scene.Update(dt)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Pass a button press to the current scene before the step it happened in, returns false once a scene quits
+func (this *SceneRunner) Press(press ButtonPress) bool {
+
+	// presses from before a stall that was skipped happen at the start of the step
+	if press.Time.Before(this.stepsEnd) {
+		press.Time = this.stepsEnd
+	}
+
+	this.held[press.Button] = press.Event == ButtonPush
+
+	if this.inputLog != nil {
+		this.inputLog.Add(this.steps, press.Time.Sub(this.stepsEnd), press)
+	}
+	return this.follow(this.scene.Button(press))
+}
+
+// Play the presses of inputLog from the current step until step until, without rendering, returns false once a scene quits.
+// The log is counted from its Start, so a replay started at step 0 plays a log recorded from there
+func (this *SceneRunner) Replay(inputLog *InputLog, until int64) bool {
+
+	for _, logged := range inputLog.Presses {
+		step := inputLog.Start + logged.Step
+		if step < this.steps {
+			continue
+		}
+		if step >= until {
+			break
+		}
+
+		if !this.Advance(int(step - this.steps)) {
+			return false
+		}
+		if !this.Press(ButtonPress{Button: logged.Button, Event: logged.Event, Time: this.stepsEnd.Add(logged.Offset)}) {
+			return false
+		}
+	}
+
+	return this.Advance(int(until - this.steps))
+}
+
+// Change to the next scene if there is one, returns false for QuitScenes
+func (this *SceneRunner) follow(next string) bool {
+
+	switch next {
+	case StayInScene:
+		return true
+	case QuitScenes:
+		this.scene.Exit()
+		return false
+	}
+
+	this.change(next)
+	return true
+}
+
+// Exit the current scene and enter the named one, starting a cross fade when one is set
+func (this *SceneRunner) change(name string) {
+
+	scene, ok := this.scenes[name]
+	if !ok {
+		log.Fatal("Unknown scene ", name)
+	}
+
+	fade, ok := this.fades[[2]string{this.current, name}]
+	if !ok {
+		fade = this.fades[[2]string{"", name}]
+	}
+
+	if this.scene != nil {
+		if fade > 0 {
+			this.fadeFrom = this.capture(this.scene.Field(), this.fadeFrom)
+			this.fadeTime, this.fadeRemaining = fade, fade
+		}
+		this.scene.Exit()
+	}
+
+	this.current, this.scene = name, scene
+	scene.Enter()
+}
+
+// Render the current scene alpha of the way between its last two steps, blended with the last frame of the previous scene while fading
+func (this *SceneRunner) render(alpha float64) {
+
+	field := this.scene.Field()
+	field.Interpolate(alpha)
+
+	if this.fadeRemaining <= 0 {
+		field.RenderTo(this.display)
+		return
+	}
+
+	this.captured = this.capture(field, this.captured)
+	if len(this.captured) != len(this.fadeFrom) {
+		this.display.Render(this.captured)
+		return
+	}
+
+	if len(this.blended) != len(this.captured) {
+		this.blended = make([]RGBA, len(this.captured))
+	}
+
+	// share of the previous scene, falls from 1 to 0
+	share := this.fadeRemaining / this.fadeTime
+	for index, color := range this.captured {
+		from := this.fadeFrom[index]
+		this.blended[index] = RGBA{
+			uint8(float64(from.R)*share + float64(color.R)*(1-share) + 0.5),
+			uint8(float64(from.G)*share + float64(color.G)*(1-share) + 0.5),
+			uint8(float64(from.B)*share + float64(color.B)*(1-share) + 0.5),
+			255,
+		}
+	}
+
+	this.display.Render(this.blended)
+}
+
+// Render field into buffer instead of a display
+func (this *SceneRunner) capture(field *GameField, buffer []RGBA) []RGBA {
+	capture := &captureDisplay{colors: buffer[:0]}
+	field.RenderTo(capture)
+	return capture.colors
+}
+
+// Keeps a copy of the rendered frame
+type captureDisplay struct {
+	colors []RGBA
+}
+
+func (this *captureDisplay) Render(colors []RGBA) {
+	this.colors = append(this.colors[:0], colors...)
+}
+
+// Button events waiting on events, in order, with any that happened after until moved back to until
+func receiveButtonPresses(events <-chan ButtonPress, until time.Time) (presses []ButtonPress) {
+	for {
+		select {
+		case press := <-events:
+			if press.Time.After(until) {
+				press.Time = until
+			}
+			presses = append(presses, press)
+		default:
+			return
+		}
+	}
+}
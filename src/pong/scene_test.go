@@ -0,0 +1,298 @@
+package pong
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Scene filling the field with one color, it moves on to next after duration or when a button is pushed
+type testScene struct {
+	SceneBase
+	field    *GameField
+	drawable *colorDrawable
+	color    RGBA
+	next     string
+	duration float64
+
+	elapsed         float64
+	updates         []float64
+	presses         []ButtonPress
+	entered, exited int
+}
+
+func newTestScene(color RGBA, next string, duration float64) *testScene {
+	scene := &testScene{field: NewGameField(2), color: color, next: next, duration: duration}
+	scene.drawable = &colorDrawable{color: color}
+	scene.field.Add(scene.drawable)
+	return scene
+}
+
+// Fills the field with a single color
+type colorDrawable struct {
+	color RGBA
+
+	// last alpha it was interpolated with
+	alpha float64
+}
+
+func (this *colorDrawable) Interpolate(alpha float64) {
+	this.alpha = alpha
+}
+
+func (this *colorDrawable) ColorAt(position float64, baseColor RGBA) RGBA {
+	return this.color
+}
+
+func (this *colorDrawable) ZIndex() ZIndex {
+	return 1
+}
+
+func (this *colorDrawable) Animate(dt float64) (keepAlive bool) {
+	return true
+}
+
+func (this *testScene) Enter() {
+	this.entered++
+	this.elapsed = 0
+}
+
+func (this *testScene) Exit() {
+	this.exited++
+}
+
+func (this *testScene) Update(dt float64) string {
+	this.updates = append(this.updates, dt)
+	this.elapsed += dt
+	if this.duration > 0 && this.elapsed >= this.duration {
+		return this.next
+	}
+	return StayInScene
+}
+
+func (this *testScene) Button(press ButtonPress) string {
+	this.presses = append(this.presses, press)
+	if press.Event == ButtonPush {
+		return this.next
+	}
+	return StayInScene
+}
+
+func (this *testScene) Field() *GameField {
+	return this.field
+}
+
+func Test_SceneRunner_TimerTransition(t *testing.T) {
+	display := &collectingDisplay{}
+	runner := NewSceneRunner(display, nil, time.Millisecond, time.Second/64)
+	first := newTestScene(RGBA{255, 0, 0, 255}, "second", 0.25)
+	second := newTestScene(RGBA{0, 0, 255, 255}, QuitScenes, 0.25)
+	runner.Add("first", first)
+	runner.Add("second", second)
+
+	start := time.Unix(1000, 0)
+	runner.Start("first", start)
+	Assert(first.entered, 1, "first entered", t)
+
+	// frames of 8 steps each
+	for frame := 1; frame <= 3; frame++ {
+		if !runner.Step(start.Add(time.Duration(frame) * 125 * time.Millisecond)) {
+			t.Fatal("quit too early")
+		}
+	}
+
+	Assert(len(first.updates), 16, "first updated for a quarter second", t)
+	Assert(first.exited, 1, "first exited", t)
+	Assert(second.entered, 1, "second entered", t)
+	if runner.Current() != "second" {
+		t.Fatal("current scene is", runner.Current())
+	}
+	Assert(int(display.frames[1][0].B), 255, "second scene rendered", t)
+
+	if runner.Step(start.Add(500 * time.Millisecond)) {
+		t.Fatal("runner should stop once a scene quits")
+	}
+	Assert(second.exited, 1, "second exited", t)
+	Assert(int(runner.Steps()), 32, "steps", t)
+}
+
+func Test_SceneRunner_ButtonsBetweenUpdates(t *testing.T) {
+	input := newButtonEvents(SettingsData{ButtonDebounceMs: -1})
+	runner := NewSceneRunner(&collectingDisplay{}, input, time.Millisecond, 10*time.Millisecond)
+	first := newTestScene(RGBA{255, 0, 0, 255}, "second", 0)
+	second := newTestScene(RGBA{0, 0, 255, 255}, StayInScene, 0)
+	runner.Add("first", first)
+	runner.Add("second", second)
+
+	start := time.Unix(1000, 0)
+	runner.Start("first", start)
+
+	// a push 25ms into a 100ms frame is handled after the step ending at 20ms
+	input.update(LeftButtonId, true, start.Add(25*time.Millisecond))
+	runner.Step(start.Add(100 * time.Millisecond))
+
+	Assert(len(first.updates), 2, "first updated until the push", t)
+	Assert(int(first.updates[0]*1000+0.5), 10, "fixed step", t)
+	Assert(len(first.presses), 1, "first got the push", t)
+	Assert(len(second.updates), 8, "second updated for the rest of the frame", t)
+	Assert(int(second.drawable.alpha*100), 0, "frame at the end of a step", t)
+
+	// half way into the next step the scene is drawn half way between its steps
+	runner.Step(start.Add(105 * time.Millisecond))
+	Assert(len(second.updates), 8, "no step yet", t)
+	Assert(int(second.drawable.alpha*100+0.5), 50, "frame half way to the next step", t)
+}
+
+func Test_SceneRunner_CrossFade(t *testing.T) {
+	display := &collectingDisplay{}
+	runner := NewSceneRunner(display, nil, time.Millisecond, 100*time.Millisecond)
+	runner.Add("red", newTestScene(RGBA{200, 0, 0, 255}, "blue", 0.1))
+	runner.Add("blue", newTestScene(RGBA{0, 0, 200, 255}, StayInScene, 0))
+	runner.SetFade("", "blue", 0.2)
+
+	start := time.Unix(1000, 0)
+	runner.Start("red", start)
+	runner.Step(start.Add(100 * time.Millisecond)) // changes to blue, fade starts
+	runner.Step(start.Add(200 * time.Millisecond)) // half way
+	runner.Step(start.Add(300 * time.Millisecond)) // done
+
+	Assert(int(display.frames[0][0].R), 200, "fade starts at the old scene", t)
+	Assert(int(display.frames[1][0].R), 100, "half of the old scene", t)
+	Assert(int(display.frames[1][0].B), 100, "half of the new scene", t)
+	Assert(int(display.frames[2][0].B), 200, "only the new scene after the fade", t)
+}
+
+// Scene moving a point by random amounts in the direction set by the last button pushed
+type randomWalkScene struct {
+	SceneBase
+	field     *GameField
+	position  float64
+	direction float64
+}
+
+func (this *randomWalkScene) Enter() {
+	this.direction = 1
+}
+
+func (this *randomWalkScene) Update(dt float64) string {
+	this.position += this.direction * this.field.Random().Float64() * dt
+	return StayInScene
+}
+
+func (this *randomWalkScene) Button(press ButtonPress) string {
+	if press.Event == ButtonPush {
+		this.direction = 1
+		if press.Button == LeftButtonId {
+			this.direction = -1
+		}
+	}
+	return StayInScene
+}
+
+func (this *randomWalkScene) Field() *GameField {
+	return this.field
+}
+
+type randomWalkSnapshot struct {
+	position, direction float64
+	field               *FieldSnapshot
+}
+
+func (this *randomWalkScene) Snapshot() interface{} {
+	return randomWalkSnapshot{this.position, this.direction, this.field.Snapshot()}
+}
+
+func (this *randomWalkScene) Restore(snapshot interface{}) {
+	saved := snapshot.(randomWalkSnapshot)
+	this.position, this.direction = saved.position, saved.direction
+	this.field.Restore(saved.field)
+}
+
+func newRandomWalkScene(seed uint64) *randomWalkScene {
+	scene := &randomWalkScene{field: NewGameField(2)}
+	scene.field.SetRandom(NewRandom(seed))
+	return scene
+}
+
+// Replaying the logged presses from the same seed plays the same game, whatever the frame times were
+func Test_SceneRunner_ReplayInputs(t *testing.T) {
+	input := newButtonEvents(SettingsData{ButtonDebounceMs: -1})
+	played := newRandomWalkScene(42)
+	runner := NewSceneRunner(&collectingDisplay{}, input, time.Millisecond, 4*time.Millisecond)
+	runner.Add("walk", played)
+
+	start := time.Unix(1000, 0)
+	runner.Start("walk", start)
+	inputLog := NewInputLog(42)
+	runner.RecordInputs(inputLog)
+
+	// uneven frames with presses between them
+	now := start
+	for frame := 0; frame < 100; frame++ {
+		if frame%7 == 3 {
+			input.update(ButtonId(frame%2), true, now.Add(time.Millisecond))
+			input.update(ButtonId(frame%2), false, now.Add(6*time.Millisecond))
+		}
+		now = now.Add(time.Duration(5+frame%11) * time.Millisecond)
+		runner.Step(now)
+	}
+
+	replayed := newRandomWalkScene(inputLog.Seed)
+	replayer := NewSceneRunner(&collectingDisplay{}, nil, time.Millisecond, 4*time.Millisecond)
+	replayer.Add("walk", replayed)
+	replayer.Start("walk", start)
+	if !replayer.Replay(inputLog, runner.Steps()) {
+		t.Fatal("replay quit")
+	}
+
+	Assert(boolToInt(len(inputLog.Presses) > 20), 1, "presses logged", t)
+	Assert(int(replayer.Steps()), int(runner.Steps()), "steps", t)
+	if math.Float64bits(replayed.position) != math.Float64bits(played.position) {
+		t.Fatal("replayed position", replayed.position, "vs played", played.position)
+	}
+	if replayed.field.Random().State() != played.field.Random().State() {
+		t.Fatal("random numbers differ after the replay")
+	}
+}
+
+// Going back to a snapshot and replaying from there ends where the game went without it
+func Test_SceneRunner_RewindSnapshot(t *testing.T) {
+	input := newButtonEvents(SettingsData{ButtonDebounceMs: -1})
+	played := newRandomWalkScene(9)
+	runner := NewSceneRunner(&collectingDisplay{}, input, time.Millisecond, 4*time.Millisecond)
+	runner.Add("walk", played)
+
+	start := time.Unix(1000, 0)
+	runner.Start("walk", start)
+
+	// a button held before recording starts is logged as pushed at its start
+	input.update(RightButtonId, true, start.Add(time.Millisecond))
+	runner.Step(start.Add(10 * time.Millisecond))
+	Assert(boolToInt(runner.Held(RightButtonId)), 1, "right held", t)
+
+	inputLog := NewInputLog(9)
+	runner.RecordInputs(inputLog)
+	Assert(len(inputLog.Presses), 1, "held button logged", t)
+	Assert(int(inputLog.Presses[0].Event), int(ButtonPush), "held button logged as push", t)
+
+	input.update(LeftButtonId, true, start.Add(21*time.Millisecond))
+	runner.Step(start.Add(40 * time.Millisecond))
+	snapshot := runner.Snapshot()
+	input.update(LeftButtonId, false, start.Add(41*time.Millisecond))
+	input.update(RightButtonId, false, start.Add(47*time.Millisecond))
+	runner.Step(start.Add(100 * time.Millisecond))
+
+	end, endSteps := played.position, runner.Steps()
+	Assert(boolToInt(runner.Held(LeftButtonId) || runner.Held(RightButtonId)), 0, "released", t)
+
+	runner.Restore(snapshot)
+	Assert(int(runner.Steps()), 10, "steps restored", t)
+	Assert(boolToInt(runner.Held(LeftButtonId)), 1, "held restored", t)
+
+	if !runner.Replay(inputLog, endSteps) {
+		t.Fatal("replay quit")
+	}
+	if math.Float64bits(played.position) != math.Float64bits(end) {
+		t.Fatal("position after rewinding", played.position, "vs", end)
+	}
+}
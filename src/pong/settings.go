@@ -2,14 +2,21 @@ package pong
 
 import (
 	"encoding/xml"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
+	"strings"
+	"time"
 )
 
 type SettingsData struct {
 	// Max frames per second, app uses thread.sleep to limit FPS
 	MaxFPS float64
 
+	// Updates of the game per second, fixed so the same button presses play the same game, defaults to 240
+	SimulationHz float64
+
 	// Number of Leds in board
 	LedCount int
 
@@ -19,6 +26,84 @@ type SettingsData struct {
 	// speed of the bus
 	SpiBusSpeedHz uint
 
+	// LED driver chip on the strip, one of LPD8806, APA102, WS2801 or WS2812 (over SPI at 2.4MHz)
+	Chipset string
+
+	// global brightness from 1 to 31 sent with every APA102 led
+	Apa102Brightness int
+
+	// gamma correction exponent used to build the lookup tables, defaults to 2.5
+	GammaExponent float64
+
+	// order the chipset expects the channels in such as GRB, defaults to the chipset's usual order
+	ColorOrder string
+
+	// white balance gain from 0 to 1 for each channel, 0 is the same as 1
+	RedGain, GreenGain, BlueGain float64
+
+	// Protocol used to send frames over the network, one of opc, e131 or artnet, empty to use SPI
+	NetworkProtocol string
+
+	// host:port of the pixel controller, port defaults to the protocol's port, e131 uses multicast and artnet broadcast if empty
+	NetworkAddress string
+
+	// first universe used by e131 and artnet, further universes are used once it is full
+	NetworkStartUniverse int
+
+	// channel in the first universe where led data starts, 0 based
+	NetworkStartChannel int
+
+	// number of channels used in each universe, defaults to 510 (170 leds)
+	NetworkChannelsPerUniverse int
+
+	// channel used in opc messages, 0 is every channel
+	OpcChannel int
+
+	// terminal display wraps long strips onto several lines instead of shrinking them to fit
+	TerminalWrap bool
+
+	// terminal display shows FPS and the game phase below the field
+	TerminalStatusLine bool
+
+	// longest stretch of a recording kept in seconds, older frames are dropped while recording, defaults to 120
+	MaxRecordingSeconds float64
+
+	// Width and height of an LED matrix panel, LedCount is set to their product, 0 when the leds are a single strip
+	MatrixWidth, MatrixHeight int
+
+	// how the matrix is wired: rowmajor, serpentine, columnmajor or columnserpentine
+	MatrixLayout string
+
+	// clockwise rotation of the field on the matrix: 0, 90, 180 or 270
+	MatrixRotation int
+
+	// tall mirrors the strip across every row of the matrix, 2d plays on the whole matrix
+	MatrixMode string
+
+	// Most current the leds may draw in milliamps, frames are dimmed to stay under it, 0 for no limit
+	MaxMilliamps float64
+
+	// current drawn by one fully lit color channel of one led in milliamps, defaults to 20
+	MilliampsPerChannel float64
+
+	// current drawn by each led while it's dark in milliamps
+	IdleMilliampsPerLed float64
+
+	// brightness from 0 to 1 applied to every frame, defaults to 1
+	Brightness float64
+
+	// brightness used from NightStartHour until NightEndHour, night mode is off when both hours are equal
+	NightBrightness float64
+
+	// hours of the day, 0 to 23, that night mode starts and ends
+	NightStartHour, NightEndHour int
+
+	// Displays that are driven at the same time, when empty a single display is picked from the command line
+	Outputs []OutputSettings `xml:"Outputs>Output"`
+
+	// Sources of button presses that are used together, when empty the GPIO buttons are used
+	Inputs []InputSettings `xml:"Inputs>Input"`
+
 	// Path to the GPIO port for the left button
 	LeftButtonPath string
 
@@ -31,6 +116,70 @@ type SettingsData struct {
 	// GPIO port for right
 	RightButtonGpioPort string
 
+	// how the buttons are read, sysfs through the paths above or chardev through GpioChip
+	GpioBackend string
+
+	// gpio character device used by the chardev backend, defaults to /dev/gpiochip0
+	GpioChip string
+
+	// offset or name of each button's line on GpioChip, defaults to the GPIO port
+	LeftButtonLine, RightButtonLine string
+
+	// lines of the inner players' buttons in doubles, only read by the chardev backend and when set
+	LeftInnerButtonLine, RightInnerButtonLine string
+
+	// bias of the button lines with the chardev backend: pull-up (default), pull-down, disabled or as-is
+	GpioBias string
+
+	// keys that work the left and right button with the keyboard input, default to a and l
+	LeftKeys, RightKeys string
+
+	// keys of the inner players in doubles, default to s and k
+	LeftInnerKeys, RightInnerKeys string
+
+	// how long a typed key holds its button down in milliseconds, defaults to 200, longer than the key repeat delay keeps a held key down
+	KeyHoldMs float64
+
+	// changes of a button within this many milliseconds of its previous change are ignored, defaults to 5, negative turns debouncing off
+	ButtonDebounceMs float64
+
+	// difficulty of a computer player on the left or right side: easy, medium or hard, empty for a person
+	LeftPlayerAI, RightPlayerAI string
+
+	// difficulty of the computer opponent picked by holding a button during the intro, defaults to medium
+	IntroAI string
+
+	// steps of the attract loop shown until a button is pushed, in an Attract section, defaults to the sinusoid, the high scores,
+	// a demo game and the color wheel
+	Attract []AttractStep `xml:"Attract>Step"`
+
+	// text blinking over the demo games, defaults to "press to play"
+	AttractPrompt string
+
+	// two players on each side, the inner one's paddle is InnerPaddleDepth leds in from the end of the field
+	Doubles bool
+
+	// leds between the end of the field and the inner paddles in doubles, defaults to a tenth of the field
+	InnerPaddleDepth int
+
+	// average seconds between power-ups appearing during play, 0 for none
+	PowerUpSeconds float64
+
+	// comma separated power-ups that appear: wide, slow, life, phantom and multiball, empty for all of them
+	PowerUps string
+
+	// timing windows of the skill shots, a hit without any is returned the same way whenever the paddle was pushed
+	Shots ShotSettings
+
+	// handicaps that can be picked for a side at the intro, in a Profiles section
+	Profiles []PlayerProfile `xml:"Profiles>Profile"`
+
+	// names of the profiles each side plays with until another is picked, empty for none
+	LeftProfile, RightProfile string
+
+	// rounds in a match, the first player to win more than half of them wins, defaults to 1
+	MatchBestOf int
+
 	// Amount of speedup
 	BounceVelocityIncrease float64
 
@@ -39,6 +188,119 @@ type SettingsData struct {
 
 	// Min time for a single frame
 	MinFrameTime float64 `xml:"-"`
+
+	// Time of a single update of the game
+	SimulationStep float64 `xml:"-"`
+}
+
+// How the timing of a hit changes the return, times are in seconds and a window of 0 turns its shot off
+type ShotSettings struct {
+
+	// a push at most Smash before the ball is hit smashes it, multiplying its speed and how steep it goes across the rows, default to 1.25 and 1.5
+	Smash      float64 `xml:"smash,attr,omitempty"`
+	SmashSpeed float64 `xml:"smashspeed,attr,omitempty"`
+	SmashAngle float64 `xml:"smashangle,attr,omitempty"`
+
+	// a paddle held longer than Soft before the ball is hit returns it softly, default to 0.8 and 0.5
+	Soft      float64 `xml:"soft,attr,omitempty"`
+	SoftSpeed float64 `xml:"softspeed,attr,omitempty"`
+	SoftAngle float64 `xml:"softangle,attr,omitempty"`
+
+	// a hit with the second of two pushes at most DoubleTap apart fakes out, the ball turns back for FakeOut seconds in the middle of the field, defaults to 0.15
+	DoubleTap float64 `xml:"doubletap,attr,omitempty"`
+	FakeOut   float64 `xml:"fakeout,attr,omitempty"`
+}
+
+// Handicap of the players on a side, listed in the Profiles section, each value left out plays as without a profile
+type PlayerProfile struct {
+
+	// announced when picked and as the winner
+	Name string `xml:"name,attr"`
+
+	// seconds of life to start with, defaults to LifeInSeconds
+	Life float64 `xml:"life,attr,omitempty"`
+
+	// leds the paddle covers, from the end of the field inwards, defaults to 1
+	Paddle float64 `xml:"paddle,attr,omitempty"`
+
+	// life used up for each second the paddle is held, defaults to 1
+	Drain float64 `xml:"drain,attr,omitempty"`
+
+	// speed-up of the balls the side returns, defaults to BounceVelocityIncrease
+	SpeedUp float64 `xml:"speedup,attr,omitempty"`
+
+	// color of the paddle and life bar as rrggbb, defaults to blue on the left and green on the right
+	Color string `xml:"color,attr,omitempty"`
+
+	// Color once read, transparent for the default
+	PaddleColor RGBA `xml:"-"`
+}
+
+// A step of the attract loop, listed in the Attract section
+type AttractStep struct {
+
+	// sinusoid, colors, scores or demo
+	Show string `xml:"show,attr"`
+
+	// longest time the step is shown, the high scores are shown until they've scrolled by and a demo until its match is over, defaults to 20
+	Seconds float64 `xml:"seconds,attr,omitempty"`
+}
+
+// How well a computer player plays, times are in seconds
+type AIDifficulty struct {
+
+	// time it takes to notice what the ball is doing
+	ReactionDelay float64
+
+	// how far ahead of the ball the paddle is pushed
+	Lead float64
+
+	// standard deviation of the push time
+	TimingJitter float64
+
+	// chance of pushing the paddle much too early on a return, wasting life
+	WasteChance float64
+
+	// chance of using the offensive hide while the ball is on its way to the opponent, and how long to hide it
+	HideChance, HideTime float64
+}
+
+// Difficulties that can be named in settings
+var AIDifficulties = map[string]AIDifficulty{
+	"easy":   {ReactionDelay: 0.30, Lead: 0.06, TimingJitter: 0.06, WasteChance: 0.30, HideChance: 0.0, HideTime: 0.0},
+	"medium": {ReactionDelay: 0.20, Lead: 0.04, TimingJitter: 0.03, WasteChance: 0.15, HideChance: 0.2, HideTime: 0.3},
+	"hard":   {ReactionDelay: 0.12, Lead: 0.03, TimingJitter: 0.01, WasteChance: 0.05, HideChance: 0.5, HideTime: 0.4},
+}
+
+// A display listed in the Outputs section
+type OutputSettings struct {
+
+	// led, web, terminal, opc, e131 or artnet
+	Type string `xml:"type,attr"`
+
+	// most frames per second sent to this display, 0 for no limit
+	MaxFPS float64 `xml:"maxfps,attr,omitempty"`
+
+	// host:port for network outputs, overrides NetworkAddress
+	Address string `xml:"address,attr,omitempty"`
+}
+
+// A source of button presses listed in the Inputs section
+type InputSettings struct {
+
+	// gpio, keyboard or evdev, the buttons on the web page are always used with a web display
+	Type string `xml:"type,attr"`
+
+	// event device of evdev inputs, like /dev/input/by-id/usb-...-event-joystick
+	Device string `xml:"device,attr,omitempty"`
+
+	// evdev key codes of the left and right buttons, default to the shoulder buttons BTN_TL and BTN_TR
+	LeftCode  int `xml:"left,attr,omitempty"`
+	RightCode int `xml:"right,attr,omitempty"`
+
+	// codes of the inner players' buttons in doubles, default to the triggers BTN_TL2 and BTN_TR2
+	LeftInnerCode  int `xml:"leftinner,attr,omitempty"`
+	RightInnerCode int `xml:"rightinner,attr,omitempty"`
 }
 
 // Global settings variable
@@ -61,9 +323,148 @@ func (settings *SettingsData) Read() {
 	if settings.MaxFPS == 0 {
 		settings.MaxFPS = 60
 	}
+	if settings.SimulationHz == 0 {
+		settings.SimulationHz = 240
+	}
 
 	// setup any derived values
 	settings.MinFrameTime = 1.0 / settings.MaxFPS
+	settings.SimulationStep = 1.0 / settings.SimulationHz
+
+	if settings.IsMatrix() {
+		settings.LedCount = settings.MatrixWidth * settings.MatrixHeight
+	}
+	settings.Shots.setDefaults()
+
+	for index := range settings.Profiles {
+		profile := &settings.Profiles[index]
+		if profile.Color == "" {
+			continue
+		}
+		if profile.PaddleColor, err = parseHexColor(profile.Color); err != nil {
+			log.Fatal("Bad color of profile ", profile.Name, ": ", err)
+		}
+	}
+	if err := settings.validate(); err != nil {
+		log.Fatal(err)
+	}
+	if settings.InnerPaddleDepth == 0 {
+		settings.InnerPaddleDepth = settings.FieldWidth() / 10
+		if settings.InnerPaddleDepth < 2 {
+			settings.InnerPaddleDepth = 2
+		}
+	}
+}
+
+// Check the names settings refer to, so a typo stops the game when it starts instead of in the middle of play
+func (settings *SettingsData) validate() error {
+	for _, name := range []string{settings.LeftProfile, settings.RightProfile} {
+		if _, ok := settings.Profile(name); !ok {
+			return fmt.Errorf("Unknown profile %q", name)
+		}
+	}
+	for _, name := range []string{settings.LeftPlayerAI, settings.RightPlayerAI, settings.IntroAI} {
+		if _, err := AIDifficultyNamed(name); name != "" && err != nil {
+			return err
+		}
+	}
+	for _, step := range settings.Attract {
+		switch step.Show {
+		case "sinusoid", "colors", "scores", "demo":
+		default:
+			return fmt.Errorf("Unknown attract step %q", step.Show)
+		}
+	}
+	return nil
+}
+
+// Fill in the effects of the shots that aren't set
+func (shots *ShotSettings) setDefaults() {
+	if shots.SmashSpeed == 0 {
+		shots.SmashSpeed = 1.25
+	}
+	if shots.SmashAngle == 0 {
+		shots.SmashAngle = 1.5
+	}
+	if shots.SoftSpeed == 0 {
+		shots.SoftSpeed = 0.8
+	}
+	if shots.SoftAngle == 0 {
+		shots.SoftAngle = 0.5
+	}
+	if shots.FakeOut == 0 {
+		shots.FakeOut = 0.15
+	}
+}
+
+// Profile named name, no handicap for an empty name, false if there's no such profile
+func (settings *SettingsData) Profile(name string) (PlayerProfile, bool) {
+	if name == "" {
+		return PlayerProfile{}, true
+	}
+	for _, profile := range settings.Profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return PlayerProfile{}, false
+}
+
+// Difficulty named name in any case, an error if there's no such difficulty
+func AIDifficultyNamed(name string) (AIDifficulty, error) {
+	difficulty, ok := AIDifficulties[strings.ToLower(name)]
+	if !ok {
+		return difficulty, fmt.Errorf("Unknown AI difficulty %q", name)
+	}
+	return difficulty, nil
+}
+
+// Parse a color written as rrggbb in hex, with or without a leading #
+func parseHexColor(text string) (color RGBA, err error) {
+	text = strings.TrimPrefix(text, "#")
+	if len(text) != 6 {
+		return color, fmt.Errorf("%q isn't rrggbb", text)
+	}
+	_, err = fmt.Sscanf(text, "%02x%02x%02x", &color.R, &color.G, &color.B)
+	color.A = 255
+	return
+}
+
+// Time the settings file was last changed, the zero time if it can't be read
+func SettingsModTime() time.Time {
+	info, err := os.Stat(settingsFile)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// True if the leds are a matrix panel instead of a single strip
+func (settings *SettingsData) IsMatrix() bool {
+	return settings.MatrixWidth > 0 && settings.MatrixHeight > 0
+}
+
+// True if settings dim the leds in any way
+func (settings *SettingsData) LimitsPower() bool {
+	return settings.MaxMilliamps > 0 ||
+		(0 < settings.Brightness && settings.Brightness < 1) ||
+		settings.NightStartHour != settings.NightEndHour
+}
+
+// Number of positions along the field
+func (settings *SettingsData) FieldWidth() int {
+	if settings.IsMatrix() {
+		return settings.MatrixWidth
+	}
+	return settings.LedCount
+}
+
+// Number of rows in the field, more than 1 only when playing 2d on a matrix
+func (settings *SettingsData) FieldHeight() int {
+	if settings.IsMatrix() && settings.MatrixMode == Matrix2D {
+		return settings.MatrixHeight
+	}
+	return 1
 }
 
 // Write settings to file
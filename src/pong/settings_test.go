@@ -0,0 +1,52 @@
+package pong
+
+import (
+	"testing"
+)
+
+func Test_SettingsData_Profile(t *testing.T) {
+	settings := SettingsData{Profiles: []PlayerProfile{{Name: "kid", Life: 8}, {Name: "pro", Drain: 1.5}}}
+
+	profile, ok := settings.Profile("kid")
+	Assert(boolToInt(ok), 1, "kid found", t)
+	Assert(int(profile.Life), 8, "kid life", t)
+
+	profile, ok = settings.Profile("")
+	Assert(boolToInt(ok), 1, "no profile found", t)
+	if profile != (PlayerProfile{}) {
+		t.Fatal("no profile has a handicap", profile)
+	}
+
+	_, ok = settings.Profile("dad")
+	Assert(boolToInt(ok), 0, "unknown profile found", t)
+}
+
+func Test_SettingsData_ParseHexColor(t *testing.T) {
+	color, err := parseHexColor("#ff8000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if color != (RGBA{255, 128, 0, 255}) {
+		t.Fatal("color", color)
+	}
+
+	for _, bad := range []string{"", "ff80", "orange", "gg0000"} {
+		if _, err := parseHexColor(bad); err == nil {
+			t.Fatal("parsed ", bad)
+		}
+	}
+}
+
+func Test_SettingsData_Validate(t *testing.T) {
+	settings := SettingsData{LeftPlayerAI: "Easy", IntroAI: "hard", Profiles: []PlayerProfile{{Name: "kid"}}, LeftProfile: "kid",
+		Attract: []AttractStep{{Show: "sinusoid"}, {Show: "scores"}, {Show: "demo"}, {Show: "colors"}}}
+	if err := settings.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bad := range []SettingsData{{RightPlayerAI: "impossible"}, {IntroAI: "meduim"}, {RightProfile: "dad"}, {Attract: []AttractStep{{Show: "demo"}, {Show: "fireworks"}}}} {
+		if err := bad.validate(); err == nil {
+			t.Fatal("accepted ", bad.RightPlayerAI, bad.IntroAI, bad.RightProfile, bad.Attract)
+		}
+	}
+}
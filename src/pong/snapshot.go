@@ -0,0 +1,55 @@
+package pong
+
+// State that can be saved and put back later, used to rewind and replay games
+type Snapshotter interface {
+
+	// Copy of the current state
+	Snapshot() interface{}
+
+	// Go back to a state returned by Snapshot, the same snapshot can be restored again
+	Restore(snapshot interface{})
+}
+
+// The drawables on a GameField with their state, and the state of its random numbers
+type FieldSnapshot struct {
+	drawables []Drawable
+
+	// state of each drawable that is a Snapshotter, nil for the others
+	states []interface{}
+
+	random uint64
+}
+
+// Save the drawables of the field and their state, drawables that aren't Snapshotters are put back as they are then
+func (field *GameField) Snapshot() *FieldSnapshot {
+
+	snapshot := &FieldSnapshot{random: field.random.State()}
+	for curElement := field.drawables.Front(); curElement != nil; curElement = curElement.Next() {
+
+		drawable := curElement.Value.(Drawable)
+
+		var state interface{}
+		if snapshotter, ok := drawable.(Snapshotter); ok {
+			state = snapshotter.Snapshot()
+		}
+
+		snapshot.drawables = append(snapshot.drawables, drawable)
+		snapshot.states = append(snapshot.states, state)
+	}
+
+	return snapshot
+}
+
+// Put back the drawables and their state from a Snapshot, including drawables that were removed since
+func (field *GameField) Restore(snapshot *FieldSnapshot) {
+
+	field.drawables.Init()
+	for index, drawable := range snapshot.drawables {
+		if snapshot.states[index] != nil {
+			drawable.(Snapshotter).Restore(snapshot.states[index])
+		}
+		field.drawables.PushBack(drawable)
+	}
+
+	field.random.SetState(snapshot.random)
+}
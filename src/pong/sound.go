@@ -4,6 +4,7 @@ import (
 	"log"
 	"os/exec"
 	"runtime"
+	"sync/atomic"
 )
 
 // Type of sound identifiers
@@ -20,6 +21,23 @@ const (
 
 var playWavCommand string
 
+// not 0 while sounds are muted
+var soundsMuted int32
+
+// Skip sounds and speech while muted, used while a game is replayed faster than it was played
+func MuteSounds(muted bool) {
+	if muted {
+		atomic.StoreInt32(&soundsMuted, 1)
+	} else {
+		atomic.StoreInt32(&soundsMuted, 0)
+	}
+}
+
+// True while sounds are muted
+func SoundsMuted() bool {
+	return atomic.LoadInt32(&soundsMuted) != 0
+}
+
 func init() {
 	if runtime.GOOS == "windows" {
 		playWavCommand = "c:/users/b.green/Desktop/sounder"
@@ -30,6 +48,10 @@ func init() {
 
 // Play the given sound
 func PlaySound(sound SoundType) {
+	if atomic.LoadInt32(&soundsMuted) != 0 {
+		return
+	}
+
 	cmd := exec.Command(playWavCommand, string(sound))
 	err := cmd.Run()
 	if err != nil {
@@ -40,6 +62,10 @@ func PlaySound(sound SoundType) {
 
 // Read the given text
 func PlayTTS(speak string) {
+	if atomic.LoadInt32(&soundsMuted) != 0 {
+		return
+	}
+
 	cmd := exec.Command("espeak", speak, "--stdout")
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
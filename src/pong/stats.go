@@ -0,0 +1,304 @@
+package pong
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// What happened in one match
+type GameStats struct {
+	Start time.Time `json:"start"`
+
+	// length of the match in seconds
+	Duration float64 `json:"duration"`
+
+	// "left" or "right", and the rounds won by the left and right player
+	Winner string `json:"winner"`
+	Rounds [2]int `json:"rounds"`
+
+	// two players on each side
+	Doubles bool `json:"doubles,omitempty"`
+
+	// difficulty of the computer players, empty for people
+	LeftPlayerAI  string `json:"leftAI,omitempty"`
+	RightPlayerAI string `json:"rightAI,omitempty"`
+
+	// names of the profiles the sides played with, empty for none
+	LeftProfile  string `json:"leftProfile,omitempty"`
+	RightProfile string `json:"rightProfile,omitempty"`
+
+	// hits in the whole match and in its longest point
+	Bounces      int `json:"bounces"`
+	LongestRally int `json:"longestRally"`
+
+	// fastest the ball went in leds / second
+	MaxBallSpeed float64 `json:"maxBallSpeed"`
+
+	// life the winner had left at the end in seconds
+	LifeLeft float64 `json:"lifeLeft"`
+}
+
+// Games in each high score list
+const highScoreCount = 5
+
+// The best games by each measure
+type HighScores struct {
+	LongestRally []GameStats `json:"longestRally"`
+	MostBounces  []GameStats `json:"mostBounces"`
+	FastestBall  []GameStats `json:"fastestBall"`
+}
+
+// High scores of all time and of today, with the number of games they were picked from
+type Leaderboard struct {
+	AllTime     HighScores `json:"allTime"`
+	Today       HighScores `json:"today"`
+	GamesPlayed int        `json:"gamesPlayed"`
+	GamesToday  int        `json:"gamesToday"`
+}
+
+// Every match played, kept in a file with one json object per line that matches are appended to
+type StatsStore struct {
+	path string
+
+	lock  sync.Mutex
+	games []GameStats
+}
+
+// Open the store at path, it's created with the first match added
+func OpenStatsStore(path string) (*StatsStore, error) {
+
+	store := &StatsStore{path: path}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := bufio.NewScanner(file)
+	for number := 1; lines.Scan(); number++ {
+		if len(bytes.TrimSpace(lines.Bytes())) == 0 {
+			continue
+		}
+
+		// a line cut short by a crash while writing only loses that match
+		var game GameStats
+		if err := json.Unmarshal(lines.Bytes(), &game); err != nil {
+			log.Print("Skipping line ", number, " of ", path, ": ", err)
+			continue
+		}
+		store.games = append(store.games, game)
+	}
+
+	return store, lines.Err()
+}
+
+// Record a match
+func (this *StatsStore) Add(game GameStats) error {
+
+	line, err := json.Marshal(game)
+	if err != nil {
+		return err
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	file, err := os.OpenFile(this.path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	// end a line torn by a crash first, or this match would be lost along with it
+	line = append(line, '\n')
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+			file.Close()
+			return err
+		}
+		if last[0] != '\n' {
+			line = append([]byte{'\n'}, line...)
+		}
+	}
+
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	this.games = append(this.games, game)
+	return nil
+}
+
+// Every match recorded, oldest first
+func (this *StatsStore) Games() []GameStats {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	return append([]GameStats(nil), this.games...)
+}
+
+// High scores of all time and of the day of now, in the local time zone
+func (this *StatsStore) Leaderboard(now time.Time) Leaderboard {
+
+	games := this.Games()
+
+	year, month, day := now.Date()
+	var today []GameStats
+	for _, game := range games {
+		if gameYear, gameMonth, gameDay := game.Start.In(now.Location()).Date(); gameYear == year && gameMonth == month && gameDay == day {
+			today = append(today, game)
+		}
+	}
+
+	return Leaderboard{
+		AllTime:     highScores(games),
+		Today:       highScores(today),
+		GamesPlayed: len(games),
+		GamesToday:  len(today),
+	}
+}
+
+// Best highScoreCount games by each measure
+func highScores(games []GameStats) HighScores {
+	return HighScores{
+		LongestRally: bestGames(games, func(game GameStats) float64 { return float64(game.LongestRally) }),
+		MostBounces:  bestGames(games, func(game GameStats) float64 { return float64(game.Bounces) }),
+		FastestBall:  bestGames(games, func(game GameStats) float64 { return game.MaxBallSpeed }),
+	}
+}
+
+// Up to highScoreCount games with the highest score, the one recorded first wins a tie
+func bestGames(games []GameStats, score func(GameStats) float64) []GameStats {
+
+	best := append([]GameStats(nil), games...)
+	sort.SliceStable(best, func(i, j int) bool { return score(best[i]) > score(best[j]) })
+
+	if len(best) > highScoreCount {
+		best = best[:highScoreCount]
+	}
+	return best
+}
+
+// Serve the leaderboard as json at /stats.json and as a page at /stats from the web server
+func (this *StatsStore) HandleHttp() {
+	http.HandleFunc("/stats.json", this.jsonHandler)
+	http.HandleFunc("/stats", this.htmlHandler)
+}
+
+// Leaderboard and every game as json
+func (this *StatsStore) jsonHandler(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-control", "max-age=0, must-revalidate, no-store")
+
+	response := struct {
+		Leaderboard
+		Games []GameStats `json:"games"`
+	}{this.Leaderboard(time.Now()), this.Games()}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Print(err)
+	}
+}
+
+// A high score list as shown on the page
+type highScoreTable struct {
+	Name string
+	Rows []highScoreRow
+}
+
+type highScoreRow struct {
+	Score, Winner, Rounds, Played string
+}
+
+// Rows of a high score list with the measure it's sorted by
+func newHighScoreTable(name string, games []GameStats, score func(GameStats) string) highScoreTable {
+	table := highScoreTable{Name: name}
+	for _, game := range games {
+		table.Rows = append(table.Rows, highScoreRow{
+			Score:  score(game),
+			Winner: game.Winner,
+			Rounds: fmt.Sprint(game.Rounds[0], "-", game.Rounds[1]),
+			Played: game.Start.Format("2006-01-02 15:04"),
+		})
+	}
+	return table
+}
+
+// Every list of the high scores
+func highScoreTables(scores HighScores) []highScoreTable {
+	return []highScoreTable{
+		newHighScoreTable("longest rally", scores.LongestRally, func(game GameStats) string { return fmt.Sprint(game.LongestRally) }),
+		newHighScoreTable("most bounces", scores.MostBounces, func(game GameStats) string { return fmt.Sprint(game.Bounces) }),
+		newHighScoreTable("fastest ball", scores.FastestBall, func(game GameStats) string { return fmt.Sprintf("%.1f", game.MaxBallSpeed) }),
+	}
+}
+
+var statsPage = template.Must(template.New("stats").Parse(`<html>
+	<head>
+		<title>High scores</title>
+		<style>
+			body { background: #111; color: #ddd; font-family: sans-serif; }
+			table { border-collapse: collapse; margin: 0 24px 24px 0; float: left; }
+			td, th { padding: 4px 12px; text-align: right; }
+			h2 { clear: both; }
+		</style>
+	</head>
+	<body>
+		<p>{{.Leaderboard.GamesToday}} matches today, {{.Leaderboard.GamesPlayed}} in all, <a href="/stats.json">json</a></p>
+		{{range .Sections}}
+		<h2>{{.Title}}</h2>
+		{{range .Tables}}
+		<table>
+			<tr><th>{{.Name}}</th><th>winner</th><th>rounds</th><th>played</th></tr>
+			{{range .Rows}}<tr><td>{{.Score}}</td><td>{{.Winner}}</td><td>{{.Rounds}}</td><td>{{.Played}}</td></tr>
+			{{end}}
+		</table>
+		{{end}}
+		{{end}}
+	</body>
+</html>`))
+
+// Page with the high scores of today and all time
+func (this *StatsStore) htmlHandler(w http.ResponseWriter, r *http.Request) {
+
+	type section struct {
+		Title  string
+		Tables []highScoreTable
+	}
+
+	leaderboard := this.Leaderboard(time.Now())
+	page := struct {
+		Leaderboard Leaderboard
+		Sections    []section
+	}{leaderboard, []section{
+		{"Today", highScoreTables(leaderboard.Today)},
+		{"All time", highScoreTables(leaderboard.AllTime)},
+	}}
+
+	w.Header().Set("Cache-control", "max-age=0, must-revalidate, no-store")
+	if err := statsPage.Execute(w, page); err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,131 @@
+package pong
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Matches added to a store are read back when it's opened again, a torn last line is skipped
+func Test_StatsStore_AddReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stats.jsonl")
+
+	store, err := OpenStatsStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	played := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	for rally := 1; rally <= 3; rally++ {
+		game := GameStats{Start: played, Winner: "left", Rounds: [2]int{2, 1}, LongestRally: rally, MaxBallSpeed: 42.5}
+		if err := store.Add(game); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"start":"2024-05-01T12:3`)
+	file.Close()
+
+	reopened, err := OpenStatsStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	games := reopened.Games()
+	Assert(len(games), 3, "games read", t)
+	Assert(games[2].LongestRally, 3, "rally", t)
+	Assert(games[2].Rounds[1], 1, "rounds", t)
+	Assert(boolToInt(games[2].Start.Equal(played)), 1, "start", t)
+	Assert(int(games[2].MaxBallSpeed*10), 425, "speed", t)
+}
+
+// A match added after a line torn by a crash is kept on a line of its own
+func Test_StatsStore_AddAfterTornLine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stats.jsonl")
+
+	store, err := OpenStatsStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rally := 1; rally <= 2; rally++ {
+		if err := store.Add(GameStats{Winner: "left", LongestRally: rally}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, info.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+
+	torn, err := OpenStatsStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := torn.Add(GameStats{Winner: "right", LongestRally: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenStatsStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	games := reopened.Games()
+	Assert(len(games), 2, "games read", t)
+	Assert(games[0].LongestRally, 1, "match before the torn line", t)
+	Assert(games[1].LongestRally, 3, "match added after the torn line", t)
+}
+
+// Only games on the day of now count for today, every list holds the best few
+func Test_StatsStore_Leaderboard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := OpenStatsStore(filepath.Join(dir, "stats.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 5, 2, 18, 0, 0, 0, time.UTC)
+	for game := 0; game < 10; game++ {
+		store.Add(GameStats{
+			Start:        now.Add(-time.Duration(game) * 6 * time.Hour),
+			Bounces:      game,
+			LongestRally: 10 - game,
+			MaxBallSpeed: float64(game % 4),
+		})
+	}
+
+	leaderboard := store.Leaderboard(now)
+	Assert(leaderboard.GamesPlayed, 10, "games played", t)
+	Assert(leaderboard.GamesToday, 4, "games today", t)
+
+	Assert(len(leaderboard.AllTime.MostBounces), highScoreCount, "list length", t)
+	Assert(leaderboard.AllTime.MostBounces[0].Bounces, 9, "most bounces", t)
+	Assert(leaderboard.AllTime.LongestRally[0].LongestRally, 10, "longest rally", t)
+	Assert(int(leaderboard.AllTime.FastestBall[0].MaxBallSpeed), 3, "fastest ball", t)
+	Assert(leaderboard.AllTime.FastestBall[0].Bounces, 3, "earlier game first on a tie", t)
+
+	Assert(len(leaderboard.Today.MostBounces), 4, "today's list", t)
+	Assert(leaderboard.Today.MostBounces[0].Bounces, 3, "most bounces today", t)
+}
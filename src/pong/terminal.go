@@ -0,0 +1,309 @@
+package pong
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A display that can show a line of status text next to the field
+type StatusDisplay interface {
+	Display
+
+	// Set the text describing what the game is currently doing
+	SetStatus(status string)
+}
+
+// Draws the field on a single line of the terminal with ANSI escape codes
+type TerminalDisplay struct {
+	out *bufio.Writer
+
+	// guards out between Render and Close, nothing is drawn once closed
+	outLock sync.Mutex
+	closed  bool
+
+	// use 24 bit color, otherwise the 256 color palette
+	trueColor bool
+
+	// wrap long strips onto several lines instead of downscaling them
+	wrap bool
+
+	// leds in each line when showing a 2d field, 0 to fit the terminal
+	rowLength int
+
+	// show the status line below the field
+	showStatus bool
+	status     string
+
+	// log output waiting to be written above the next frame, so it doesn't break up the frame drawn in place
+	logs []byte
+
+	statusLock sync.Mutex
+
+	// width of the terminal in characters, refreshed once a second
+	columns        int
+	columnsChecked time.Time
+
+	// number of lines drawn by the previous frame, the cursor is moved back up over them
+	linesDrawn int
+
+	// frames rendered since fpsStart, used for the status line
+	frameCount int
+	fpsStart   time.Time
+	fps        float64
+
+	// reused buffer of the colors that are drawn
+	scaled []RGBA
+}
+
+var _ StatusDisplay = &TerminalDisplay{}
+var _ io.Writer = &TerminalDisplay{}
+
+// Construct a TerminalDisplay writing to stdout, the log is written above the field until the display is closed.
+// The cursor is shown again on Cleanup or when the process is interrupted
+func NewTerminalDisplay(settings SettingsData) *TerminalDisplay {
+	display := newTerminalDisplay(os.Stdout, settings)
+	log.SetOutput(display)
+	AtExit(func() {
+		display.Close()
+		log.SetOutput(os.Stderr)
+	})
+	return display
+}
+
+// Construct a TerminalDisplay writing to out
+func newTerminalDisplay(out io.Writer, settings SettingsData) *TerminalDisplay {
+
+	colorTerm := strings.ToLower(os.Getenv("COLORTERM"))
+
+	display := &TerminalDisplay{
+		out:        bufio.NewWriter(out),
+		trueColor:  colorTerm == "truecolor" || colorTerm == "24bit",
+		wrap:       settings.TerminalWrap,
+		showStatus: settings.TerminalStatusLine,
+		fpsStart:   time.Now(),
+	}
+
+	// a 2d field is drawn one row per line
+	if settings.FieldHeight() > 1 {
+		display.wrap = true
+		display.rowLength = settings.FieldWidth()
+	}
+
+	// hide the cursor so it doesn't flicker at the end of the line
+	display.out.WriteString("\x1b[?25l")
+
+	return display
+}
+
+// Set the text shown on the status line
+func (this *TerminalDisplay) SetStatus(status string) {
+	this.statusLock.Lock()
+	defer this.statusLock.Unlock()
+
+	this.status = status
+}
+
+// Keep log output to write it above the next frame
+func (this *TerminalDisplay) Write(text []byte) (int, error) {
+	this.statusLock.Lock()
+	defer this.statusLock.Unlock()
+
+	this.logs = append(this.logs, text...)
+	return len(text), nil
+}
+
+// Write the log output kept since the previous frame, each line clears what was drawn there before
+func (this *TerminalDisplay) writeLogs() {
+	this.statusLock.Lock()
+	logs := this.logs
+	this.logs = nil
+	this.statusLock.Unlock()
+
+	for _, line := range strings.SplitAfter(string(logs), "\n") {
+		if line != "" {
+			this.out.WriteString("\x1b[0m\x1b[2K")
+			this.out.WriteString(line)
+		}
+	}
+	if len(logs) > 0 && logs[len(logs)-1] != '\n' {
+		this.out.WriteString("\n")
+	}
+}
+
+// Reset the colors and show the cursor again below the field, later frames aren't drawn
+func (this *TerminalDisplay) Close() {
+	this.outLock.Lock()
+	defer this.outLock.Unlock()
+
+	if this.closed {
+		return
+	}
+	this.closed = true
+
+	this.out.WriteString("\x1b[0m\x1b[?25h\n")
+	this.writeLogs()
+	this.out.Flush()
+}
+
+// Redraw the field in place
+func (this *TerminalDisplay) Render(colors []RGBA) {
+	this.outLock.Lock()
+	defer this.outLock.Unlock()
+
+	if this.closed {
+		return
+	}
+
+	this.updateFps()
+
+	if time.Since(this.columnsChecked) > time.Second {
+		this.columns = terminalColumns()
+		this.columnsChecked = time.Now()
+	}
+
+	// move back to the start of the previous frame
+	if this.linesDrawn > 1 {
+		fmt.Fprintf(this.out, "\x1b[%dA", this.linesDrawn-1)
+	}
+	this.out.WriteString("\r")
+
+	// log lines take the place of the top of the previous frame, the frame is drawn again below them
+	this.writeLogs()
+
+	lineLength := this.columns
+	if this.rowLength > 0 {
+		lineLength = this.rowLength
+	}
+
+	lines := 0
+	if this.wrap {
+		for start := 0; start < len(colors); start += lineLength {
+			end := start + lineLength
+			if end > len(colors) {
+				end = len(colors)
+			}
+			if lines > 0 {
+				this.out.WriteString("\n")
+			}
+			this.writeColors(colors[start:end])
+			lines++
+		}
+	} else {
+		this.writeColors(this.downscale(colors, this.columns))
+		lines++
+	}
+
+	if this.showStatus {
+		this.statusLock.Lock()
+		status := fmt.Sprintf("%5.1f fps  %s", this.fps, this.status)
+		this.statusLock.Unlock()
+
+		// a status wrapping onto another line would throw off moving back up
+		if runes := []rune(status); this.columns > 0 && len(runes) >= this.columns {
+			status = string(runes[:this.columns-1])
+		}
+		this.out.WriteString("\n\x1b[0m\x1b[2K")
+		this.out.WriteString(status)
+		lines++
+	}
+
+	this.linesDrawn = lines
+	this.out.Flush()
+}
+
+// Write one space per color with the color as background, then reset the attributes
+func (this *TerminalDisplay) writeColors(colors []RGBA) {
+
+	var previous RGBA
+	for index, color := range colors {
+
+		// only change colors when needed, neighboring leds are often the same
+		if index == 0 || color != previous {
+			if this.trueColor {
+				this.out.WriteString("\x1b[48;2;")
+				this.out.WriteString(strconv.Itoa(int(color.R)))
+				this.out.WriteByte(';')
+				this.out.WriteString(strconv.Itoa(int(color.G)))
+				this.out.WriteByte(';')
+				this.out.WriteString(strconv.Itoa(int(color.B)))
+				this.out.WriteByte('m')
+			} else {
+				this.out.WriteString("\x1b[48;5;")
+				this.out.WriteString(strconv.Itoa(ansi256(color)))
+				this.out.WriteByte('m')
+			}
+			previous = color
+		}
+
+		this.out.WriteByte(' ')
+	}
+
+	this.out.WriteString("\x1b[0m\x1b[K")
+}
+
+// Average neighboring colors so the field fits in columns characters
+func (this *TerminalDisplay) downscale(colors []RGBA, columns int) []RGBA {
+
+	if columns <= 0 || len(colors) <= columns {
+		return colors
+	}
+
+	if len(this.scaled) != columns {
+		this.scaled = make([]RGBA, columns)
+	}
+
+	for column := range this.scaled {
+		start := column * len(colors) / columns
+		end := (column + 1) * len(colors) / columns
+
+		var red, green, blue int
+		for _, color := range colors[start:end] {
+			red += int(color.R)
+			green += int(color.G)
+			blue += int(color.B)
+		}
+
+		count := end - start
+		this.scaled[column] = RGBA{uint8(red / count), uint8(green / count), uint8(blue / count), 255}
+	}
+
+	return this.scaled
+}
+
+// Recompute frames per second once a second
+func (this *TerminalDisplay) updateFps() {
+	this.frameCount++
+
+	elapsed := time.Since(this.fpsStart).Seconds()
+	if elapsed >= 1.0 {
+		this.fps = float64(this.frameCount) / elapsed
+		this.frameCount = 0
+		this.fpsStart = time.Now()
+	}
+}
+
+// Closest color in the 6x6x6 cube of the 256 color palette
+func ansi256(color RGBA) int {
+	toCube := func(value uint8) int {
+		return (int(value)*5 + 127) / 255
+	}
+	return 16 + 36*toCube(color.R) + 6*toCube(color.G) + toCube(color.B)
+}
+
+// Width of the terminal, falls back to $COLUMNS and then 80
+func terminalColumns() int {
+	if columns := terminalWidth(); columns > 0 {
+		return columns
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return 80
+}
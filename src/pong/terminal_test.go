@@ -0,0 +1,96 @@
+package pong
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_TerminalDisplay_Render(t *testing.T) {
+	t.Setenv("COLORTERM", "truecolor")
+	t.Setenv("COLUMNS", "80")
+
+	var out bytes.Buffer
+	display := newTerminalDisplay(&out, SettingsData{LedCount: 3})
+
+	red, blue := RGBA{255, 0, 0, 255}, RGBA{0, 0, 255, 255}
+	display.Render([]RGBA{red, red, blue})
+
+	expected := "\x1b[?25l\r\x1b[48;2;255;0;0m  \x1b[48;2;0;0;255m \x1b[0m\x1b[K"
+	if out.String() != expected {
+		t.Fatalf("frame %q, expected %q", out.String(), expected)
+	}
+
+	out.Reset()
+	display.Render([]RGBA{blue, blue, blue})
+	if out.String() != "\r\x1b[48;2;0;0;255m   \x1b[0m\x1b[K" {
+		t.Fatalf("second frame %q", out.String())
+	}
+}
+
+func Test_TerminalDisplay_Render_256Colors(t *testing.T) {
+	t.Setenv("COLORTERM", "")
+	t.Setenv("COLUMNS", "80")
+
+	var out bytes.Buffer
+	display := newTerminalDisplay(&out, SettingsData{LedCount: 2})
+	display.Render([]RGBA{{255, 0, 0, 255}, {0, 0, 0, 255}})
+
+	if !strings.HasSuffix(out.String(), "\r\x1b[48;5;196m \x1b[48;5;16m \x1b[0m\x1b[K") {
+		t.Fatalf("frame %q", out.String())
+	}
+}
+
+// Log output is written over the top of the previous frame and the frame is drawn again below it
+func Test_TerminalDisplay_Logs(t *testing.T) {
+	t.Setenv("COLORTERM", "truecolor")
+	t.Setenv("COLUMNS", "80")
+
+	var out bytes.Buffer
+	display := newTerminalDisplay(&out, SettingsData{LedCount: 1})
+	display.Render([]RGBA{{0, 0, 0, 255}})
+
+	out.Reset()
+	fmt.Fprint(display, "first\nsecond")
+	display.Render([]RGBA{{0, 0, 0, 255}})
+
+	expected := "\r\x1b[0m\x1b[2Kfirst\n\x1b[0m\x1b[2Ksecond\n\x1b[48;2;0;0;0m \x1b[0m\x1b[K"
+	if out.String() != expected {
+		t.Fatalf("frame %q, expected %q", out.String(), expected)
+	}
+	Assert(display.linesDrawn, 1, "lines moved back up over", t)
+}
+
+// The status line is cut to the width of the terminal so it never wraps
+func Test_TerminalDisplay_StatusWidth(t *testing.T) {
+	t.Setenv("COLUMNS", "20")
+
+	var out bytes.Buffer
+	display := newTerminalDisplay(&out, SettingsData{LedCount: 4, TerminalStatusLine: true})
+	display.SetStatus("round 1, playing a very long match")
+	display.Render(make([]RGBA, 4))
+
+	lines := strings.Split(out.String(), "\n")
+	Assert(len(lines), 2, "lines", t)
+	status := strings.TrimPrefix(lines[1], "\x1b[0m\x1b[2K")
+	Assert(len(status), 19, "status width", t)
+	if !strings.HasSuffix(status, "round 1,") {
+		t.Fatalf("status %q", status)
+	}
+}
+
+func Test_TerminalDisplay_Close(t *testing.T) {
+	var out bytes.Buffer
+	display := newTerminalDisplay(&out, SettingsData{LedCount: 2})
+	display.Close()
+
+	if !strings.HasSuffix(out.String(), "\x1b[?25h\n") {
+		t.Fatalf("cursor not shown again %q", out.String())
+	}
+
+	out.Reset()
+	display.Render([]RGBA{{255, 0, 0, 255}, {0, 0, 0, 255}})
+	display.Close()
+	Assert(out.Len(), 0, "output after close", t)
+}
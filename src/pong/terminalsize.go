@@ -0,0 +1,24 @@
+// +build !windows
+
+package pong
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Size of the terminal as returned by TIOCGWINSZ
+type terminalWindowSize struct {
+	rows, columns, xPixels, yPixels uint16
+}
+
+// Width of the terminal attached to stdout, 0 if it isn't a terminal
+func terminalWidth() int {
+	var size terminalWindowSize
+	_, _, err := syscall.Syscall(syscall.SYS_IOCTL, os.Stdout.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+	if err != 0 {
+		return 0
+	}
+	return int(size.columns)
+}
@@ -0,0 +1,8 @@
+// +build windows
+
+package pong
+
+// Width of the terminal, not implemented on windows so $COLUMNS is used instead
+func terminalWidth() int {
+	return 0
+}
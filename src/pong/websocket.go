@@ -0,0 +1,246 @@
+package pong
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Minimal server side of RFC 6455, only what the web display needs
+
+// Opcodes used in websocket frames
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// GUID that is appended to the client key during the handshake
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// largest message accepted from a client, they only ever send small control messages
+const wsMaxClientPayload = 1 << 16
+
+// time allowed for a single frame to be written before the client is considered dead
+const wsWriteTimeout = 2 * time.Second
+
+// A websocket connection that has completed the handshake
+type webSocketConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	// serializes writes between the frame pusher and control frame replies
+	writeLock sync.Mutex
+}
+
+// Compute the Sec-WebSocket-Accept value for the given client key
+func webSocketAcceptKey(clientKey string) string {
+	hash := sha1.Sum([]byte(clientKey + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// Returns true if the comma separated header contains token, ignoring case
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Perform the websocket handshake and take over the underlying connection
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*webSocketConn, error) {
+
+	if r.Method != "GET" ||
+		!headerContainsToken(r.Header, "Connection", "upgrade") ||
+		!headerContainsToken(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, errors.New("not a websocket upgrade request")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported websocket version")
+	}
+
+	clientKey := r.Header.Get("Sec-WebSocket-Key")
+	if clientKey == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("missing Sec-WebSocket-Key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection can't be hijacked", http.StatusInternalServerError)
+		return nil, errors.New("response does not support hijacking")
+	}
+
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + webSocketAcceptKey(clientKey) + "\r\n\r\n"
+
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &webSocketConn{
+		conn:   conn,
+		reader: buffered.Reader,
+	}, nil
+}
+
+// Append the header and payload of an unmasked frame to buffer
+func appendWebSocketFrame(buffer []byte, opcode byte, payload []byte) []byte {
+
+	buffer = append(buffer, 0x80|opcode) // FIN is always set, messages are never fragmented
+
+	length := len(payload)
+	switch {
+	case length < 126:
+		buffer = append(buffer, byte(length))
+	case length <= 0xFFFF:
+		buffer = append(buffer, 126, byte(length>>8), byte(length))
+	default:
+		buffer = append(buffer, 127)
+		var extended [8]byte
+		binary.BigEndian.PutUint64(extended[:], uint64(length))
+		buffer = append(buffer, extended[:]...)
+	}
+
+	return append(buffer, payload...)
+}
+
+// Write a single message to the client
+func (this *webSocketConn) WriteMessage(opcode byte, payload []byte) error {
+
+	frame := appendWebSocketFrame(make([]byte, 0, len(payload)+10), opcode, payload)
+
+	this.writeLock.Lock()
+	defer this.writeLock.Unlock()
+
+	this.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	_, err := this.conn.Write(frame)
+	return err
+}
+
+// Read the next data message from the client, answering pings along the way
+func (this *webSocketConn) ReadMessage() (opcode byte, payload []byte, err error) {
+
+	for {
+		frameOpcode, final, data, err := readWebSocketFrame(this.reader)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch frameOpcode {
+		case wsPing:
+			if err := this.WriteMessage(wsPong, data); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			this.WriteMessage(wsClose, nil)
+			return 0, nil, io.EOF
+		case wsContinuation:
+			if opcode == 0 {
+				return 0, nil, errors.New("unexpected continuation frame")
+			}
+		default:
+			opcode = frameOpcode
+			payload = payload[:0]
+		}
+
+		payload = append(payload, data...)
+		if len(payload) > wsMaxClientPayload {
+			return 0, nil, errors.New("websocket message too large")
+		}
+
+		if final {
+			return opcode, payload, nil
+		}
+	}
+}
+
+// Read and unmask a single frame
+func readWebSocketFrame(reader io.Reader) (opcode byte, final bool, payload []byte, err error) {
+
+	var header [2]byte
+	if _, err = io.ReadFull(reader, header[:]); err != nil {
+		return
+	}
+
+	final = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err = io.ReadFull(reader, extended[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err = io.ReadFull(reader, extended[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+
+	if length > wsMaxClientPayload {
+		err = errors.New("websocket frame too large")
+		return
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(reader, mask[:]); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(reader, payload); err != nil {
+		return
+	}
+
+	if masked {
+		for index := range payload {
+			payload[index] ^= mask[index%4]
+		}
+	}
+
+	return
+}
+
+// Close the underlying connection
+func (this *webSocketConn) Close() error {
+	return this.conn.Close()
+}
@@ -0,0 +1,108 @@
+package pong
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Accept key must match the example from RFC 6455
+func Test_WebSocket_AcceptKey(t *testing.T) {
+	key := webSocketAcceptKey("dGhlIHNhbXBsZSBub25jZQ==")
+	if key != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatal("Wrong accept key", key)
+	}
+}
+
+// Frames written by the server should be readable, for every length encoding
+func Test_WebSocket_FrameRoundTrip(t *testing.T) {
+	for _, length := range []int{0, 5, 125, 126, 300, 70000} {
+		payload := bytes.Repeat([]byte{7}, length)
+
+		frame := appendWebSocketFrame(nil, wsBinary, payload)
+		opcode, final, data, err := readWebSocketFrame(bytes.NewReader(frame))
+
+		if length > wsMaxClientPayload {
+			if err == nil {
+				t.Fatal("Expected oversized frame to be rejected, length", length)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opcode != wsBinary || !final || !bytes.Equal(data, payload) {
+			t.Fatal("Frame did not round trip, length", length)
+		}
+	}
+}
+
+// Connect to the stream of a test server and complete the handshake
+func dialStream(server *httptest.Server, t *testing.T) (net.Conn, *bufio.Reader) {
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	request := "GET /stream HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Assert(response.StatusCode, http.StatusSwitchingProtocols, "Handshake status", t)
+
+	return conn, reader
+}
+
+// A connected client should receive rendered frames as packed RGB
+func Test_WebDisplay_Stream(t *testing.T) {
+	display := &WebDisplay{frames: NewFrameStore()}
+	server := httptest.NewServer(http.HandlerFunc(display.streamHandler))
+	defer server.Close()
+
+	conn, reader := dialStream(server, t)
+	defer conn.Close()
+
+	display.Render([]RGBA{{1, 2, 3, 255}, {4, 5, 6, 255}})
+
+	opcode, _, data, err := readWebSocketFrame(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opcode != wsBinary || !bytes.Equal(data, []byte{1, 2, 3, 4, 5, 6}) {
+		t.Fatal("Unexpected frame", opcode, data)
+	}
+}
+
+// Button messages from the page should push the buttons, and leaving should release them
+func Test_WebDisplay_Buttons(t *testing.T) {
+	display := &WebDisplay{frames: NewFrameStore(), buttons: newButtonEvents(SettingsData{ButtonDebounceMs: -1})}
+	server := httptest.NewServer(http.HandlerFunc(display.streamHandler))
+	defer server.Close()
+
+	conn, _ := dialStream(server, t)
+	conn.Write(appendWebSocketFrame(nil, wsText, []byte("right down")))
+
+	press := <-display.Input().Events()
+	Assert(int(press.Button), int(RightButtonId), "button", t)
+	Assert(int(press.Event), int(ButtonPush), "event", t)
+	Assert(boolToInt(display.Input().RightButton()), 1, "right down", t)
+
+	conn.Close()
+
+	press = <-display.Input().Events()
+	Assert(int(press.Event), int(ButtonRelease), "released when the client left", t)
+	Assert(boolToInt(display.Input().RightButton()), 0, "right up", t)
+}
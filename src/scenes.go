@@ -0,0 +1,1324 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	. "pong"
+	. "pong/draw"
+	"time"
+)
+
+// Names the scenes are registered under
+const (
+	sceneIntro       = "intro"
+	sceneOpening     = "opening"
+	scenePlay        = "play"
+	sceneScoreboard  = "scoreboard"
+	sceneClosing     = "closing"
+	sceneCalibration = "calibration"
+	sceneReplay      = "replay"
+	sceneProfiles    = "profiles"
+)
+
+// how long a button is held during the intro to play against the computer, in seconds
+const introHoldTime = 1.5
+
+// seconds the profiles are shown without a push before going back to the intro
+const profileIdleTime = 15.0
+
+// most power-ups waiting on the field at once, and how long each waits to be collected in seconds
+const maxPowerUps, powerUpLifeTime = 2, 10.0
+
+// most balls in play at once, a multi ball power-up splits off fewer when there are already more
+const maxBalls = 3
+
+// steps of the attract loop without any in settings, and how long a step without seconds is shown
+var defaultAttract = []AttractStep{{Show: "sinusoid"}, {Show: "scores"}, {Show: "demo", Seconds: 60}, {Show: "colors"}}
+
+const attractStepTime = 20.0
+
+// difficulties the computers of a demo game are picked from
+var demoDifficulties = []string{"easy", "medium", "hard"}
+
+// most of the last point shown again after a match, in seconds, and how fast it's shown
+const instantReplayTime, instantReplaySpeed = 3.0, 0.5
+
+// updates between the snapshots a replay is rewound to
+const replayKeyframeSteps = 240
+
+// State shared by the scenes of a match
+type session struct {
+	display  Display
+	buttons  InputSource
+	recorder *RecordingDisplay
+
+	// true while a match is played again, nothing is announced, recorded or saved then
+	replaying bool
+
+	// difficulty of the computer on each side, empty for a person
+	leftAI, rightAI string
+
+	// handicap of each side
+	leftProfile, rightProfile PlayerProfile
+
+	match *Match
+
+	// runner playing the scenes, random numbers of the match and the button presses that drive it
+	runner *SceneRunner
+	random *Random
+	inputs *InputLog
+
+	// when the match started, and its length in updates once it's over
+	played     time.Time
+	matchSteps int64
+
+	// snapshot at the start of the current point, shown again as an instant replay after the last one
+	pointStart *SceneSnapshot
+
+	// bounces in the current round
+	bounces int
+
+	// statistics of the match and the hits in its current point, matches are added to statsStore when it isn't nil
+	stats      GameStats
+	rally      int
+	statsStore *StatsStore
+}
+
+// Register every scene of the game with runner
+func addGameScenes(runner *SceneRunner, game *session) {
+
+	runner.Add(sceneIntro, &introScene{game: game})
+	runner.Add(sceneProfiles, &profileScene{game: game})
+	addMatchScenes(runner, game)
+
+	runner.SetFade(sceneIntro, sceneOpening, 0.3)
+	runner.SetFade(sceneIntro, sceneProfiles, 0.3)
+	runner.SetFade(sceneProfiles, sceneIntro, 0.3)
+	runner.SetFade(scenePlay, sceneScoreboard, 0.3)
+	runner.SetFade(scenePlay, sceneClosing, 0.3)
+	runner.SetFade(sceneClosing, sceneIntro, 0.5)
+}
+
+// Register the scenes of a match from its opening to its closing with runner
+func addMatchScenes(runner *SceneRunner, game *session) {
+
+	game.runner = runner
+	runner.Add(sceneOpening, &openingScene{game: game})
+	runner.Add(scenePlay, &playScene{game: game})
+	runner.Add(sceneScoreboard, &scoreboardScene{game: game})
+	runner.Add(sceneClosing, &closingScene{game: game})
+}
+
+// Start a match with the computer playing the sides with a difficulty and random numbers from seed
+func (this *session) startMatch(leftAI, rightAI string, seed uint64) {
+
+	this.leftAI, this.rightAI = leftAI, rightAI
+	this.played = time.Now()
+
+	// the seed and the logged presses replay the match
+	this.random = NewRandom(seed)
+	this.inputs = NewInputLog(seed)
+
+	this.match = NewMatch(Settings.MatchBestOf, this.random.Float64() < 0.5)
+
+	this.stats = GameStats{Start: this.played, Doubles: Settings.Doubles, LeftPlayerAI: leftAI, RightPlayerAI: rightAI,
+		LeftProfile: this.leftProfile.Name, RightProfile: this.rightProfile.Name}
+	this.rally = 0
+}
+
+// Count hits of the ball and how fast it goes for the statistics
+func (this *session) trackPlay(hits int, ball *Ball) {
+
+	this.stats.Bounces += hits
+	this.rally += hits
+	if this.rally > this.stats.LongestRally {
+		this.stats.LongestRally = this.rally
+	}
+	if speed := ball.Speed(); speed > this.stats.MaxBallSpeed {
+		this.stats.MaxBallSpeed = speed
+	}
+}
+
+// Stop logging presses once the match is over, add it to the statistics and save it as a replay
+func (this *session) finishMatch(winnerLife float64) {
+
+	this.matchSteps = this.runner.Steps() - this.inputs.Start
+	this.runner.RecordInputs(nil)
+	log.Print("Match played with seed ", this.inputs.Seed, ", ", this.matchSteps, " updates and ", len(this.inputs.Presses), " button presses")
+
+	this.recordStats(winnerLife)
+	this.saveReplay()
+}
+
+// Add the match that's over to the statistics
+func (this *session) recordStats(winnerLife float64) {
+
+	if this.statsStore == nil {
+		return
+	}
+
+	stats := this.stats
+	stats.Duration = float64(this.matchSteps) * Settings.SimulationStep
+	stats.Rounds[0], stats.Rounds[1] = this.match.Score()
+	stats.Winner = "right"
+	if this.match.LeftWon() {
+		stats.Winner = "left"
+	}
+	stats.LifeLeft = winnerLife
+
+	go func() {
+		if err := this.statsStore.Add(stats); err != nil {
+			log.Print("Failed to save statistics ", err)
+		}
+	}()
+}
+
+// Save the match that's over as a replay when there's a directory for them
+func (this *session) saveReplay() {
+
+	if *replayDir == "" {
+		return
+	}
+
+	replay := &GameReplay{
+		Played:                 this.played,
+		Width:                  Settings.FieldWidth(),
+		Height:                 Settings.FieldHeight(),
+		SimulationHz:           Settings.SimulationHz,
+		LifeInSeconds:          Settings.LifeInSeconds,
+		BounceVelocityIncrease: Settings.BounceVelocityIncrease,
+		MatchBestOf:            Settings.MatchBestOf,
+		Doubles:                Settings.Doubles,
+		InnerPaddleDepth:       Settings.InnerPaddleDepth,
+		PowerUpSeconds:         Settings.PowerUpSeconds,
+		PowerUps:               Settings.PowerUps,
+		Shots:                  Settings.Shots,
+		LeftProfile:            this.leftProfile,
+		RightProfile:           this.rightProfile,
+		LeftPlayerAI:           this.leftAI,
+		RightPlayerAI:          this.rightAI,
+		Steps:                  this.matchSteps,
+		Inputs:                 *this.inputs,
+	}
+	name := "pongpi-" + this.played.Format("20060102-150405")
+
+	go func() {
+		if err := SaveGameReplay(*replayDir, name, replay); err != nil {
+			log.Print("Failed to save replay ", err)
+			return
+		}
+		log.Print("Saved replay ", name)
+	}()
+}
+
+// Copy of this session playing a match again with runner
+func (this *session) replayWith(runner *SceneRunner) *session {
+	replay := *this
+	replay.replaying = true
+	replay.runner = runner
+	replay.recorder = nil
+	return &replay
+}
+
+// Runner for the scenes of a match played again, it's only moved forward with Replay
+func newReplayRunner(display Display) *SceneRunner {
+	step := time.Duration(Settings.SimulationStep * float64(time.Second))
+	return NewSceneRunner(display, nil, step, step)
+}
+
+// State of the match saved with the snapshot of each scene
+type matchSnapshot struct {
+	match   Match
+	bounces int
+	random  uint64
+}
+
+func (this *session) snapshot() matchSnapshot {
+	return matchSnapshot{match: *this.match, bounces: this.bounces, random: this.random.State()}
+}
+
+func (this *session) restore(snapshot matchSnapshot) {
+	match := snapshot.match
+	this.match, this.bounces = &match, snapshot.bounces
+	this.random.SetState(snapshot.random)
+}
+
+// Snapshot of a game scene, scene is a copy of the scene itself
+type sceneSnapshot struct {
+	scene interface{}
+	field *FieldSnapshot
+	match matchSnapshot
+
+	// state of the computer players of a playScene
+	computers [ButtonCount]interface{}
+}
+
+// Difficulty of the computer on a side, empty for people
+func (this *session) ai(left bool) string {
+	if left {
+		return this.leftAI
+	}
+	return this.rightAI
+}
+
+// Handicap of the players on a side
+func (this *session) profile(left bool) *PlayerProfile {
+	if left {
+		return &this.leftProfile
+	}
+	return &this.rightProfile
+}
+
+// Name of a player used in announcements, the name of its profile when it has one
+func (this *session) playerName(left bool) string {
+	if name := this.profile(left).Name; name != "" {
+		return name
+	}
+	if left {
+		return "Blue"
+	}
+	return "Green"
+}
+
+// Attract loop of animations, high scores and demo games until a button is pushed. Holding a button for introHoldTime
+// starts a game against the computer, pushing a button of the other side while holding one picks the profiles
+type introScene struct {
+	SceneBase
+	game  *session
+	field *GameField
+
+	// button that was pushed and how long it has been held
+	pushed bool
+	button ButtonId
+	held   float64
+
+	// step of the attract loop shown and the time left of it
+	step      int
+	remaining float64
+
+	// plays a match between two computers during a demo step, nil during the others
+	demo *SceneRunner
+}
+
+func (this *introScene) Enter() {
+	setStatus(this.game.display, "intro")
+
+	this.pushed, this.held = false, 0
+	this.showStep(0)
+}
+
+// Show step index of the attract loop, skipping steps with nothing to show
+func (this *introScene) showStep(index int) {
+
+	this.stopDemo()
+
+	steps := Settings.Attract
+	if len(steps) == 0 {
+		steps = defaultAttract
+	}
+
+	this.field = newField()
+	for tries := 0; tries < len(steps); tries++ {
+		this.step = (index + tries) % len(steps)
+		step := steps[this.step]
+
+		this.remaining = step.Seconds
+		if this.remaining <= 0 {
+			this.remaining = attractStepTime
+		}
+
+		switch step.Show {
+		case "sinusoid":
+			this.field.Add(NewSinusoid(this.field, 1))
+			return
+		case "colors":
+			this.field.Add(NewHSLWheel(this.field, 1))
+			return
+		case "scores":
+			if this.game.statsStore == nil {
+				continue
+			}
+			entries := leaderboardEntries(this.game.statsStore.Leaderboard(time.Now()))
+			if len(entries) == 0 {
+				continue
+			}
+			ticker := NewTicker(this.field, entries, 50)
+			this.field.Add(NewSinusoid(this.field, 1))
+			this.field.Add(ticker)
+			this.remaining = ticker.Duration()
+			return
+		case "demo":
+			this.startDemo()
+			return
+		default:
+			log.Print("Skipping unknown attract step ", step.Show)
+		}
+	}
+
+	// none of the steps has anything to show yet
+	this.field.Add(NewSinusoid(this.field, 1))
+}
+
+// Play a match between two computers of random difficulties below a blinking prompt, without sound
+func (this *introScene) startDemo() {
+
+	seed := uint64(time.Now().UnixNano())
+	random := NewRandom(seed)
+
+	this.demo = newReplayRunner(this.game.display)
+	demoGame := this.game.replayWith(this.demo)
+	demoGame.leftProfile, demoGame.rightProfile = PlayerProfile{}, PlayerProfile{}
+
+	// the status stays on the intro
+	demoGame.display = nil
+	addMatchScenes(this.demo, demoGame)
+	this.demo.Add(sceneIntro, &demoOverScene{field: newField()})
+
+	demoGame.startMatch(demoDifficulties[random.Intn(len(demoDifficulties))], demoDifficulties[random.Intn(len(demoDifficulties))], seed)
+	this.demo.Start(sceneOpening, time.Now())
+
+	prompt := Settings.AttractPrompt
+	if prompt == "" {
+		prompt = "press to play"
+	}
+	this.field.Add(NewFieldLayer(this.demo.Field, 0))
+	this.field.Add(NewPrompt(this.field, prompt, RGBA{255, 255, 255, 255}, 100))
+
+	MuteSounds(true)
+	setStatus(this.game.display, "intro, demo game")
+}
+
+// Leave the demo game, if one is played
+func (this *introScene) stopDemo() {
+	if this.demo != nil {
+		this.demo = nil
+		MuteSounds(false)
+		setStatus(this.game.display, "intro")
+	}
+}
+
+func (this *introScene) Button(press ButtonPress) string {
+
+	if press.Event == ButtonPush && !this.pushed {
+		this.pushed, this.button = true, press.Button
+
+		// a push leaves the attract loop at once
+		this.stopDemo()
+		this.field = newField()
+		this.field.Add(NewSinusoid(this.field, 1))
+	} else if press.Event == ButtonPush && press.Button.IsLeft() != this.button.IsLeft() && len(Settings.Profiles) > 0 {
+		return sceneProfiles
+	} else if press.Event == ButtonRelease && this.pushed && press.Button == this.button {
+
+		// a short push starts the game as configured
+		return this.startMatch(Settings.LeftPlayerAI, Settings.RightPlayerAI)
+	}
+
+	return StayInScene
+}
+
+func (this *introScene) Update(dt float64) string {
+
+	this.field.Animate(dt)
+
+	if !this.pushed {
+		// the demo's match scenes go back to the intro once it's over
+		if this.demo != nil {
+			this.demo.Advance(1)
+		}
+		this.remaining -= dt
+		if this.remaining <= 0 || (this.demo != nil && this.demo.Current() == sceneIntro) {
+			this.showStep(this.step + 1)
+		}
+	}
+
+	if this.pushed {
+		this.held += dt
+		if this.held >= introHoldTime {
+
+			// the player holding the button plays against the computer
+			difficulty := Settings.IntroAI
+			if difficulty == "" {
+				difficulty = "medium"
+			}
+			log.Print("Single player game against ", difficulty, " computer")
+
+			if this.button.IsLeft() {
+				return this.startMatch(Settings.LeftPlayerAI, difficulty)
+			}
+			return this.startMatch(difficulty, Settings.RightPlayerAI)
+		}
+	}
+
+	return StayInScene
+}
+
+// Start a match with the computer playing the sides with a difficulty
+func (this *introScene) startMatch(leftAI, rightAI string) string {
+
+	this.game.startMatch(leftAI, rightAI, uint64(time.Now().UnixNano()))
+	this.game.runner.RecordInputs(this.game.inputs)
+
+	if this.game.recorder != nil && *recordGames {
+		this.game.recorder.StartRecording()
+	}
+
+	return sceneOpening
+}
+
+func (this *introScene) Exit() {
+	this.stopDemo()
+}
+
+func (this *introScene) Field() *GameField {
+	return this.field
+}
+
+// Stands in for the intro in the runner of a demo game, which is over once its match scenes go back to it
+type demoOverScene struct {
+	SceneBase
+	field *GameField
+}
+
+func (this *demoOverScene) Update(dt float64) string {
+	return StayInScene
+}
+
+func (this *demoOverScene) Field() *GameField {
+	return this.field
+}
+
+// Best scores of today and of all time scrolled by during the intro, none before the first match
+func leaderboardEntries(leaderboard Leaderboard) []TickerEntry {
+
+	var entries []TickerEntry
+	add := func(title string, scores HighScores) {
+		if len(scores.LongestRally) == 0 {
+			return
+		}
+
+		// bars on a strip are a white pip for the title and the scores scaled to a few leds
+		rally, bounces, speed := scores.LongestRally[0].LongestRally, scores.MostBounces[0].Bounces, scores.FastestBall[0].MaxBallSpeed
+		entries = append(entries,
+			TickerEntry{Text: title, Bar: 1, Color: RGBA{255, 255, 255, 255}},
+			TickerEntry{Text: fmt.Sprint("rally ", rally), Bar: rally, Color: RGBA{255, 160, 0, 255}},
+			TickerEntry{Text: fmt.Sprint("bounces ", bounces), Bar: bounces / 4, Color: RGBA{255, 0, 160, 255}},
+			TickerEntry{Text: fmt.Sprintf("speed %.0f", speed), Bar: int(speed / 10), Color: RGBA{0, 200, 255, 255}},
+		)
+	}
+	add("today", leaderboard.Today)
+	add("best", leaderboard.AllTime)
+
+	return entries
+}
+
+// Shows the profile of each side, a short push of a side's button changes to its next profile and holding one goes back to the intro
+type profileScene struct {
+	SceneBase
+	game  *session
+	field *GameField
+
+	// how long each button has been held, negative while it's up or held since the intro
+	held [ButtonCount]float64
+
+	// seconds until going back to the intro without a push
+	idle float64
+}
+
+func (this *profileScene) Enter() {
+	setStatus(this.game.display, "picking profiles")
+
+	for button := range this.held {
+		this.held[button] = -1
+	}
+	this.idle = profileIdleTime
+	this.show()
+
+	go PlayTTS("Push your button to change your profile, hold it when you're done")
+}
+
+// Draw a player with the profile of each side, their life bars are as long as the life they start with
+func (this *profileScene) show() {
+
+	most := Settings.LifeInSeconds
+	for _, profile := range Settings.Profiles {
+		most = math.Max(most, profile.Life)
+	}
+
+	this.field = newField()
+	for _, left := range []bool{true, false} {
+		profile := this.game.profile(left)
+
+		life := profile.Life
+		if life == 0 {
+			life = Settings.LifeInSeconds
+		}
+
+		player := NewPlayer(left, most, this.field)
+		player.Handicap(0, profile.Paddle, 0, 0)
+		player.DecreaseLife(most - life)
+		if profile.PaddleColor.A != 0 {
+			player.SetColor(profile.PaddleColor)
+		}
+		player.UpdatePaddleActive(true)
+		this.field.Add(player)
+	}
+}
+
+// Change a side to the profile after its current one, no profile comes after the last
+func (this *profileScene) next(left bool) {
+
+	options := append([]PlayerProfile{{}}, Settings.Profiles...)
+	profile, next := this.game.profile(left), options[0]
+	for index, option := range options {
+		if option.Name == profile.Name {
+			next = options[(index+1)%len(options)]
+		}
+	}
+	*profile = next
+	this.show()
+
+	if next.Name == "" {
+		go PlayTTS("no handicap")
+	} else {
+		go PlayTTS(next.Name)
+	}
+}
+
+func (this *profileScene) Button(press ButtonPress) string {
+
+	this.idle = profileIdleTime
+	if press.Event == ButtonPush {
+		this.held[press.Button] = 0
+	} else if this.held[press.Button] >= 0 {
+		this.held[press.Button] = -1
+		this.next(press.Button.IsLeft())
+	}
+
+	return StayInScene
+}
+
+func (this *profileScene) Update(dt float64) string {
+
+	this.field.Animate(dt)
+
+	for button := range this.held {
+		if this.held[button] < 0 {
+			continue
+		}
+		this.held[button] += dt
+		if this.held[button] >= introHoldTime {
+			go PlayTTS("ready")
+			return sceneIntro
+		}
+	}
+
+	this.idle -= dt
+	if this.idle <= 0 {
+		return sceneIntro
+	}
+	return StayInScene
+}
+
+func (this *profileScene) Field() *GameField {
+	return this.field
+}
+
+// Countdown before each round
+type openingScene struct {
+	SceneBase
+	game      *session
+	field     *GameField
+	countDown *Countdown
+}
+
+func (this *openingScene) Enter() {
+	setStatus(this.game.display, fmt.Sprint("round ", this.game.match.Round(), ", opening"))
+
+	this.field = newField()
+	this.countDown = NewCountdown(this.field, 2)
+	this.field.Add(this.countDown)
+
+	go PlaySound(GAMESTART)
+}
+
+func (this *openingScene) Update(dt float64) string {
+
+	this.field.Animate(dt)
+
+	if this.countDown.TimeRemaining() <= 0 {
+		return scenePlay
+	}
+	return StayInScene
+}
+
+func (this *openingScene) Field() *GameField {
+	return this.field
+}
+
+func (this *openingScene) Snapshot() interface{} {
+	return &sceneSnapshot{scene: *this, field: this.field.Snapshot(), match: this.game.snapshot()}
+}
+
+func (this *openingScene) Restore(snapshot interface{}) {
+	saved, game := snapshot.(*sceneSnapshot), this.game
+	*this = saved.scene.(openingScene)
+	this.game = game
+	this.field.Restore(saved.field)
+	game.restore(saved.match)
+}
+
+// A round of play, until a player runs out of life
+type playScene struct {
+	SceneBase
+	game  *session
+	field *GameField
+
+	// balls in play, the first one is served and more are split off it by the multi ball power-up
+	balls []*Ball
+
+	// power-ups on the field, their kinds that appear and the time until the next one does
+	powerUps     []*PowerUp
+	powerUpKinds []PowerUpKind
+	untilPowerUp float64
+
+	// player of each button, the inner ones only in doubles, and the computer playing each player or nil
+	players   [ButtonCount]*Player
+	computers [ButtonCount]*AIPlayer
+
+	// every player on the field
+	inPlay []*Player
+}
+
+func (this *playScene) Enter() {
+	setStatus(this.game.display, fmt.Sprint("round ", this.game.match.Round(), ", playing"))
+
+	this.field = newField()
+	this.field.SetRandom(this.game.random)
+
+	ball := NewBallServing(this.field, this.game.match.LeftServes())
+	this.balls = []*Ball{ball}
+	this.field.Add(ball)
+
+	if this.field.Height() > 1 {
+		this.field.Add(NewWalls(this.field, RGBA{40, 40, 40, 255}, 5))
+	}
+
+	this.players = [ButtonCount]*Player{}
+	if Settings.Doubles {
+		depth := float64(Settings.InnerPaddleDepth)
+		this.players[LeftButtonId], this.players[LeftInnerButtonId] = NewDoublesPlayers(true, depth, Settings.LifeInSeconds, this.field)
+		this.players[RightButtonId], this.players[RightInnerButtonId] = NewDoublesPlayers(false, depth, Settings.LifeInSeconds, this.field)
+	} else {
+		this.players[LeftButtonId] = NewPlayer(true, Settings.LifeInSeconds, this.field)
+		this.players[RightButtonId] = NewPlayer(false, Settings.LifeInSeconds, this.field)
+	}
+
+	// computer players ignore the buttons of their side
+	this.computers = [ButtonCount]*AIPlayer{}
+	this.inPlay = nil
+	for button, player := range this.players {
+		if player == nil {
+			continue
+		}
+		this.inPlay = append(this.inPlay, player)
+		this.field.Add(player)
+
+		// the profile handicaps every player of its side, the inner player keeps its own color
+		profile := this.game.profile(ButtonId(button).IsLeft())
+		player.Handicap(profile.Life, profile.Paddle, profile.Drain, profile.SpeedUp)
+		if profile.PaddleColor.A != 0 && (ButtonId(button) == LeftButtonId || ButtonId(button) == RightButtonId) {
+			player.SetColor(profile.PaddleColor)
+		}
+		player.UpdatePaddleActive(this.game.runner.Held(ButtonId(button)))
+
+		// settings are checked when read, a replay may still name a difficulty this version doesn't have
+		if difficulty := this.game.ai(ButtonId(button).IsLeft()); difficulty != "" {
+			computer, err := NewAIPlayer(this.field, player, ball, difficulty)
+			if err != nil {
+				log.Print(err, ", the player is left to the buttons")
+			}
+			this.computers[button] = computer
+		}
+	}
+
+	// the random numbers are only used for power-ups when they're on, so games without them play the same as before
+	this.powerUps, this.powerUpKinds = nil, nil
+	if Settings.PowerUpSeconds > 0 {
+		// settings are checked at startup, a replay may still name a power-up this version doesn't have
+		var err error
+		if this.powerUpKinds, err = ParsePowerUpKinds(Settings.PowerUps); err != nil {
+			log.Print(err, ", every kind is used")
+		}
+		this.untilPowerUp = this.nextPowerUpTime()
+	}
+
+	this.game.bounces = 0
+	this.startPoint()
+}
+
+// Remember the start of a point for the instant replay
+func (this *playScene) startPoint() {
+	this.game.rally = 0
+	if !this.game.replaying {
+		this.game.pointStart = this.game.runner.Snapshot()
+	}
+}
+
+func (this *playScene) Button(press ButtonPress) string {
+
+	// the paddle moves at the moment of the press during the next update, so a hit doesn't depend on the frame rate
+	delay := press.Time.Sub(this.game.runner.Time()).Seconds()
+
+	if player := this.players[press.Button]; player != nil && this.computers[press.Button] == nil {
+		player.UpdatePaddleActiveAt(press.Event == ButtonPush, delay)
+	}
+
+	return StayInScene
+}
+
+func (this *playScene) Update(dt float64) string {
+
+	for _, computer := range this.computers {
+		if computer != nil {
+			computer.FollowNearest(this.balls)
+			computer.Update(dt)
+		}
+	}
+
+	this.field.Animate(dt)
+	this.spawnPowerUp(dt)
+
+	// every ball has to be returned, misses of balls split off the served one take them out of play
+	for _, ball := range this.balls {
+		ball.UpdateOffensiveHide(this.inPlay)
+		ball.ShowSmashZone(this.inPlay, Settings.Shots)
+
+		playerMissed, hits := ball.MissedByPlayer(this.inPlay, Settings.BounceVelocityIncrease, Settings.Shots)
+		this.game.trackPlay(hits, ball)
+		if hits > 0 {
+			this.game.bounces += hits
+			setStatus(this.game.display, fmt.Sprint("playing, ", this.game.bounces, " bounces"))
+		}
+		this.collectPowerUps(ball)
+
+		if playerMissed == nil {
+			continue
+		}
+
+		lastBall := len(this.balls) == 1
+		if lastBall {
+			ball.ResetPosition(this.field)
+		} else {
+			this.removeBall(ball)
+		}
+		if this.decreaseLife(playerMissed.IsLeft(), 0.75) {
+			return this.endRound(!playerMissed.IsLeft())
+		}
+		if lastBall {
+			this.startPoint()
+		}
+	}
+
+	return StayInScene
+}
+
+// Seconds until the next power-up appears, around PowerUpSeconds
+func (this *playScene) nextPowerUpTime() float64 {
+	return Settings.PowerUpSeconds * (0.5 + this.field.Random().Float64())
+}
+
+// Put a power-up of a random kind somewhere in the middle of the field once it's time for one
+func (this *playScene) spawnPowerUp(dt float64) {
+
+	var waiting []*PowerUp
+	for _, powerUp := range this.powerUps {
+		if !powerUp.Done() {
+			waiting = append(waiting, powerUp)
+		}
+	}
+	this.powerUps = waiting
+
+	if len(this.powerUpKinds) == 0 {
+		return
+	}
+	this.untilPowerUp -= dt
+	if this.untilPowerUp > 0 {
+		return
+	}
+	this.untilPowerUp = this.nextPowerUpTime()
+
+	if len(this.powerUps) >= maxPowerUps {
+		return
+	}
+
+	random := this.field.Random()
+	kind := this.powerUpKinds[random.Intn(len(this.powerUpKinds))]
+	width := float64(this.field.Width())
+	position := math.Floor(width*0.3 + random.Float64()*width*0.4)
+	y := float64(random.Intn(this.field.Height()))
+
+	powerUp := NewPowerUp(kind, position, y, powerUpLifeTime)
+	this.powerUps = append(this.powerUps, powerUp)
+	this.field.Add(powerUp)
+}
+
+// Give the power-ups ball went through to the side that hit it
+func (this *playScene) collectPowerUps(ball *Ball) {
+	for _, powerUp := range this.powerUps {
+		hitter := powerUp.Collect(ball)
+		if hitter == nil {
+			continue
+		}
+
+		var side []*Player
+		for _, player := range this.inPlay {
+			if player.IsLeft() == hitter.IsLeft() {
+				side = append(side, player)
+			}
+		}
+
+		this.addBalls(ApplyPowerUp(powerUp.Kind(), side, ball))
+
+		if !this.game.replaying {
+			go PlayTTS(powerUp.Kind().Name())
+		}
+	}
+}
+
+// Put balls into play, those over maxBalls are left out
+func (this *playScene) addBalls(balls []*Ball) {
+	for _, ball := range balls {
+		if len(this.balls) >= maxBalls {
+			return
+		}
+		this.balls = append(this.balls, ball)
+		this.field.Add(ball)
+	}
+}
+
+// Take a ball out of play
+func (this *playScene) removeBall(ball *Ball) {
+	var balls []*Ball
+	for _, other := range this.balls {
+		if other != ball {
+			balls = append(balls, other)
+		}
+	}
+	this.balls = balls
+	ball.Remove()
+}
+
+// Decrease the life of every player on a side, true once all of them are out of life
+func (this *playScene) decreaseLife(left bool, dt float64) (out bool) {
+	out = true
+	for _, player := range this.inPlay {
+		if player.IsLeft() == left && !player.DecreaseLife(dt) {
+			out = false
+		}
+	}
+	return
+}
+
+// Life left of the players on a side together
+func (this *playScene) life(left bool) (life float64) {
+	for _, player := range this.inPlay {
+		if player.IsLeft() == left {
+			life += player.Life()
+		}
+	}
+	return
+}
+
+// Record the winner of the round and announce it
+func (this *playScene) endRound(leftWon bool) string {
+
+	match := this.game.match
+	match.EndRound(leftWon)
+
+	left, right := match.Score()
+	if this.game.replaying {
+		if match.IsOver() {
+			return sceneClosing
+		}
+		return sceneScoreboard
+	}
+
+	if !match.IsOver() {
+		go PlayTTS(fmt.Sprint(this.game.playerName(leftWon), " wins the round. ", left, " to ", right))
+		setStatus(this.game.display, fmt.Sprint("round over, ", this.game.bounces, " bounces, ", left, " to ", right))
+		return sceneScoreboard
+	}
+
+	this.game.finishMatch(this.life(leftWon))
+	go PlayTTS(fmt.Sprint(this.game.playerName(leftWon), " wins the match. ", left, " to ", right))
+	setStatus(this.game.display, fmt.Sprint("game over, ", this.game.bounces, " bounces, ", left, " to ", right))
+	return sceneClosing
+}
+
+func (this *playScene) Field() *GameField {
+	return this.field
+}
+
+func (this *playScene) Snapshot() interface{} {
+	scene := *this
+	scene.balls = append([]*Ball(nil), this.balls...)
+	scene.powerUps = append([]*PowerUp(nil), this.powerUps...)
+
+	snapshot := &sceneSnapshot{scene: scene, field: this.field.Snapshot(), match: this.game.snapshot()}
+	for button, computer := range this.computers {
+		if computer != nil {
+			snapshot.computers[button] = computer.Snapshot()
+		}
+	}
+	return snapshot
+}
+
+func (this *playScene) Restore(snapshot interface{}) {
+	saved, game := snapshot.(*sceneSnapshot), this.game
+	*this = saved.scene.(playScene)
+	this.game = game
+	this.balls = append([]*Ball(nil), this.balls...)
+	this.powerUps = append([]*PowerUp(nil), this.powerUps...)
+	this.field.Restore(saved.field)
+	for button, computer := range this.computers {
+		if computer != nil {
+			computer.Restore(saved.computers[button])
+		}
+	}
+	game.restore(saved.match)
+}
+
+// Rounds won so far, shown between the rounds of a match
+type scoreboardScene struct {
+	SceneBase
+	game       *session
+	field      *GameField
+	scoreboard *Scoreboard
+}
+
+func (this *scoreboardScene) Enter() {
+	this.field = newField()
+	left, right := this.game.match.Score()
+	this.scoreboard = NewScoreboard(this.field, left, right, this.game.match.RoundsToWin(), 2.5)
+	this.field.Add(this.scoreboard)
+}
+
+func (this *scoreboardScene) Update(dt float64) string {
+
+	this.field.Animate(dt)
+
+	if this.scoreboard.TimeRemaining() <= 0 {
+		return sceneOpening
+	}
+	return StayInScene
+}
+
+func (this *scoreboardScene) Field() *GameField {
+	return this.field
+}
+
+func (this *scoreboardScene) Snapshot() interface{} {
+	return &sceneSnapshot{scene: *this, field: this.field.Snapshot(), match: this.game.snapshot()}
+}
+
+func (this *scoreboardScene) Restore(snapshot interface{}) {
+	saved, game := snapshot.(*sceneSnapshot), this.game
+	*this = saved.scene.(scoreboardScene)
+	this.game = game
+	this.field.Restore(saved.field)
+	game.restore(saved.match)
+}
+
+// Instant replay of the last point followed by an animation showing the winner of the match
+type closingScene struct {
+	SceneBase
+	game          *session
+	field         *GameField
+	winnerDisplay *Winner
+
+	// plays the last point again until its last update, nil once the instant replay is over
+	replay    *SceneRunner
+	replayEnd int64
+
+	// time of the instant replay not played yet
+	replayLag float64
+}
+
+func (this *closingScene) Enter() {
+	this.field = newField()
+	this.winnerDisplay = NewWinner(this.field, this.game.match.LeftWon(), 4)
+	this.field.Add(this.winnerDisplay)
+
+	//go PlaySound(GAMEOVER)
+
+	this.replay = nil
+	if !this.game.replaying && this.game.pointStart != nil {
+		this.startInstantReplay()
+	}
+}
+
+// Play the last point again from its snapshot, skipping to its last instantReplayTime seconds
+func (this *closingScene) startInstantReplay() {
+
+	this.replay = newReplayRunner(this.game.display)
+	play := &playScene{game: this.game.replayWith(this.replay)}
+	this.replay.Add(scenePlay, play)
+	this.replay.Add(sceneClosing, &frozenScene{scene: play})
+	this.replay.Restore(this.game.pointStart)
+
+	this.replayEnd = this.game.inputs.Start + this.game.matchSteps
+	this.replayLag = 0
+
+	// the replay is shown without sound
+	MuteSounds(true)
+	if start := this.replayEnd - int64(instantReplayTime/Settings.SimulationStep); start > this.replay.Steps() {
+		this.replay.Replay(this.game.inputs, start)
+	}
+
+	setStatus(this.game.display, "instant replay")
+}
+
+// Leave the instant replay for the winner animation
+func (this *closingScene) stopInstantReplay() {
+	this.replay = nil
+	MuteSounds(false)
+}
+
+func (this *closingScene) Button(press ButtonPress) string {
+
+	// a push skips the instant replay
+	if this.replay != nil && press.Event == ButtonPush {
+		this.stopInstantReplay()
+	}
+	return StayInScene
+}
+
+func (this *closingScene) Update(dt float64) string {
+
+	if this.replay != nil {
+		this.replayLag += dt * instantReplaySpeed
+		for this.replayLag >= Settings.SimulationStep && this.replay.Steps() < this.replayEnd {
+			this.replayLag -= Settings.SimulationStep
+			this.replay.Replay(this.game.inputs, this.replay.Steps()+1)
+		}
+
+		// hold the last frame for a moment
+		if this.replay.Steps() >= this.replayEnd && this.replayLag > 0.5*instantReplaySpeed {
+			this.stopInstantReplay()
+		}
+		return StayInScene
+	}
+
+	this.field.Animate(dt)
+
+	if this.winnerDisplay.TimeRemaining() <= 0 {
+		return sceneIntro
+	}
+	return StayInScene
+}
+
+func (this *closingScene) Exit() {
+	if this.replay != nil {
+		this.stopInstantReplay()
+	}
+
+	if this.game.recorder != nil && *recordGames {
+		saveRecording(this.game.recorder)
+	}
+}
+
+func (this *closingScene) Field() *GameField {
+	if this.replay != nil {
+		return this.replay.Field()
+	}
+	return this.field
+}
+
+func (this *closingScene) Snapshot() interface{} {
+	return &sceneSnapshot{scene: *this, field: this.field.Snapshot(), match: this.game.snapshot()}
+}
+
+func (this *closingScene) Restore(snapshot interface{}) {
+	saved, game := snapshot.(*sceneSnapshot), this.game
+	*this = saved.scene.(closingScene)
+	this.game = game
+	this.field.Restore(saved.field)
+	game.restore(saved.match)
+}
+
+// Keeps showing the field of another scene as it was left, ends the instant replay
+type frozenScene struct {
+	SceneBase
+	scene Scene
+}
+
+func (this *frozenScene) Update(dt float64) string {
+	return StayInScene
+}
+
+func (this *frozenScene) Field() *GameField {
+	return this.scene.Field()
+}
+
+// Plays a saved match, holding the left button rewinds it and holding the right button fast forwards
+type replayScene struct {
+	SceneBase
+	game   *session
+	replay *GameReplay
+
+	// plays the match, with a snapshot every replayKeyframeSteps updates from its start
+	runner    *SceneRunner
+	keyframes []*SceneSnapshot
+
+	// speed relative to the match as it was played, negative rewinds, and the time not played yet
+	speed, lag float64
+}
+
+func (this *replayScene) Enter() {
+
+	this.runner = newReplayRunner(this.game.display)
+	replayGame := this.game.replayWith(this.runner)
+	addMatchScenes(this.runner, replayGame)
+
+	replayGame.leftProfile, replayGame.rightProfile = this.replay.LeftProfile, this.replay.RightProfile
+	replayGame.startMatch(this.replay.LeftPlayerAI, this.replay.RightPlayerAI, this.replay.Inputs.Seed)
+	replayGame.inputs = &this.replay.Inputs
+
+	this.runner.Start(sceneOpening, time.Now())
+	this.keyframes = []*SceneSnapshot{this.runner.Snapshot()}
+	this.speed, this.lag = 1, 0
+
+	log.Printf("Replaying the match of %s, %.1f seconds", this.replay.Played.Format("2006-01-02 15:04:05"), float64(this.replay.Steps)*Settings.SimulationStep)
+	setStatus(this.game.display, "replay")
+}
+
+func (this *replayScene) Button(press ButtonPress) string {
+
+	this.speed = 1
+	switch {
+	case this.game.runner.Held(LeftButtonId):
+		this.speed = -3
+	case this.game.runner.Held(RightButtonId):
+		this.speed = 4
+	}
+
+	// sounds only play at the speed of the match
+	MuteSounds(this.speed != 1)
+	setStatus(this.game.display, fmt.Sprintf("replay at %vx, %.1f seconds", this.speed, float64(this.runner.Steps())*Settings.SimulationStep))
+
+	return StayInScene
+}
+
+func (this *replayScene) Update(dt float64) string {
+
+	this.lag += dt * this.speed
+	steps := int64(this.lag / Settings.SimulationStep)
+	this.lag -= float64(steps) * Settings.SimulationStep
+
+	target := this.runner.Steps() + steps
+	if target < 0 {
+		target = 0
+	} else if target > this.replay.Steps {
+		target = this.replay.Steps
+	}
+	this.seek(target)
+
+	return StayInScene
+}
+
+// Move the match to update step, going back to the last snapshot before it to rewind
+func (this *replayScene) seek(step int64) {
+
+	if step < this.runner.Steps() {
+		this.runner.Restore(this.keyframes[step/replayKeyframeSteps])
+	}
+
+	// save snapshots on the way the first time through
+	for next := int64(len(this.keyframes)) * replayKeyframeSteps; next <= step; next += replayKeyframeSteps {
+		this.runner.Replay(&this.replay.Inputs, next)
+		this.keyframes = append(this.keyframes, this.runner.Snapshot())
+	}
+
+	this.runner.Replay(&this.replay.Inputs, step)
+}
+
+func (this *replayScene) Exit() {
+	MuteSounds(false)
+}
+
+func (this *replayScene) Field() *GameField {
+	return this.runner.Field()
+}
+
+// Test patterns, the buttons step through them and calibration settings are applied whenever settings.xml changes
+type calibrationScene struct {
+	SceneBase
+	game    *session
+	field   *GameField
+	pattern *TestPattern
+
+	// settings file time when it was last read, and the time since it was checked
+	modTime   time.Time
+	sinceRead float64
+}
+
+func (this *calibrationScene) Enter() {
+	this.field = newField()
+	this.pattern = NewTestPattern(this.field, 1)
+	this.field.Add(this.pattern)
+	this.modTime = SettingsModTime()
+
+	this.showPattern()
+}
+
+// Log and show the name of the current pattern
+func (this *calibrationScene) showPattern() {
+	log.Print("Calibrating, showing ", this.pattern.Name())
+	setStatus(this.game.display, "calibrating, "+this.pattern.Name())
+}
+
+func (this *calibrationScene) Button(press ButtonPress) string {
+
+	if press.Event != ButtonPush {
+		return StayInScene
+	}
+
+	if press.Button.IsLeft() {
+		this.pattern.Previous()
+	} else {
+		this.pattern.Next()
+	}
+	this.showPattern()
+
+	return StayInScene
+}
+
+func (this *calibrationScene) Update(dt float64) string {
+
+	this.sinceRead += dt
+	if this.sinceRead < 1 {
+		return StayInScene
+	}
+	this.sinceRead = 0
+
+	if changed := SettingsModTime(); !changed.Equal(this.modTime) {
+		this.modTime = changed
+
+		// read into fresh settings so lists like Outputs aren't appended to the old ones
+		var settings SettingsData
+		settings.Read()
+		Settings = settings
+
+		log.Printf("Applying GammaExponent %v, ColorOrder %q and gains %v %v %v",
+			Settings.GammaExponent, Settings.ColorOrder, Settings.RedGain, Settings.GreenGain, Settings.BlueGain)
+		Calibrate(this.game.display, Settings)
+	}
+
+	return StayInScene
+}
+
+func (this *calibrationScene) Field() *GameField {
+	return this.field
+}
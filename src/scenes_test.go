@@ -0,0 +1,147 @@
+package main
+
+import (
+	. "pong"
+	. "pong/draw"
+	"testing"
+)
+
+// A multi ball power-up only adds balls up to maxBalls
+func Test_PlayScene_AddBalls(t *testing.T) {
+	field := NewGameField(60)
+	ball := NewBall(field)
+	scene := &playScene{field: field, balls: []*Ball{ball}}
+	field.Add(ball)
+
+	scene.addBalls(ball.Split(1))
+	Assert(len(scene.balls), 2, "balls in play", t)
+	Assert(field.DrawableLen(), 2, "balls on the field", t)
+
+	scene.addBalls(ball.Split(2))
+	Assert(len(scene.balls), maxBalls, "balls in play over the limit", t)
+	Assert(field.DrawableLen(), maxBalls, "balls on the field over the limit", t)
+}
+
+// Settings of a 60 led strip playing one round, restored once the test is over
+func useSettings(settings SettingsData, t *testing.T) {
+	saved := Settings
+	settings.LedCount, settings.MatchBestOf, settings.LifeInSeconds = 60, 1, 4
+	settings.SimulationHz, settings.SimulationStep = 240, 1.0/240
+	Settings = settings
+	t.Cleanup(func() { Settings = saved })
+}
+
+// Display that keeps the last status it was given
+type statusRecorder struct {
+	status string
+}
+
+func (this *statusRecorder) Render(colors []RGBA) {}
+
+func (this *statusRecorder) SetStatus(status string) {
+	this.status = status
+}
+
+// Steps are shown for their seconds in turn, those with nothing to show are skipped
+func Test_IntroScene_AttractCycle(t *testing.T) {
+	useSettings(SettingsData{Attract: []AttractStep{{Show: "sinusoid", Seconds: 1}, {Show: "scores"}, {Show: "fireworks"}, {Show: "colors", Seconds: 2}}}, t)
+	scene := &introScene{game: &session{}}
+
+	scene.Enter()
+	Assert(scene.step, 0, "first step", t)
+	scene.Update(0.5)
+	Assert(scene.step, 0, "step before its time is up", t)
+
+	// no high scores without a stats file and no such step as fireworks
+	scene.Update(0.5)
+	Assert(scene.step, 3, "step after the time is up", t)
+	Assert(int(scene.remaining), 2, "time of the step", t)
+
+	scene.Update(2)
+	Assert(scene.step, 0, "step after the last one", t)
+}
+
+// A demo game plays without sound below the prompt and leaves the status to the intro, a push ends it
+func Test_IntroScene_Demo(t *testing.T) {
+	useSettings(SettingsData{Attract: []AttractStep{{Show: "demo"}}}, t)
+	display := &statusRecorder{}
+	scene := &introScene{game: &session{display: display}}
+
+	scene.Enter()
+	if scene.demo == nil {
+		t.Fatal("no demo game")
+	}
+	Assert(boolToInt(SoundsMuted()), 1, "sounds muted", t)
+	Assert(scene.field.DrawableLen(), 2, "demo game and prompt", t)
+
+	for steps := 0; scene.demo.Current() != scenePlay && steps < 240*10; steps++ {
+		scene.Update(1.0 / 240)
+	}
+	if scene.demo.Current() != scenePlay {
+		t.Fatal("demo game in", scene.demo.Current())
+	}
+	if display.status != "intro, demo game" {
+		t.Fatal("status", display.status)
+	}
+
+	scene.Button(ButtonPress{Button: RightButtonId, Event: ButtonPush})
+	if scene.demo != nil {
+		t.Fatal("demo game still played")
+	}
+	Assert(boolToInt(SoundsMuted()), 0, "sounds muted after the demo", t)
+	if display.status != "intro" {
+		t.Fatal("status", display.status)
+	}
+}
+
+// Holding a button plays against the computer on the other side, a short push plays as configured
+func Test_IntroScene_HoldForComputer(t *testing.T) {
+	useSettings(SettingsData{IntroAI: "hard", Attract: []AttractStep{{Show: "colors"}}}, t)
+	game := &session{runner: newReplayRunner(nil)}
+	scene := &introScene{game: game}
+
+	scene.Enter()
+	scene.Button(ButtonPress{Button: LeftButtonId, Event: ButtonPush})
+	if next := scene.Update(1); next != StayInScene {
+		t.Fatal("left the intro before the hold time", next)
+	}
+	if next := scene.Update(introHoldTime - 1); next != sceneOpening {
+		t.Fatal("not started after the hold time", next)
+	}
+	if game.leftAI != "" || game.rightAI != "hard" {
+		t.Fatal("computers", game.leftAI, game.rightAI)
+	}
+
+	Settings.IntroAI = ""
+	scene.Enter()
+	scene.Button(ButtonPress{Button: RightButtonId, Event: ButtonPush})
+	scene.Update(introHoldTime)
+	if game.leftAI != "medium" || game.rightAI != "" {
+		t.Fatal("computers without IntroAI", game.leftAI, game.rightAI)
+	}
+
+	Settings.LeftPlayerAI = "easy"
+	scene.Enter()
+	scene.Button(ButtonPress{Button: LeftButtonId, Event: ButtonPush})
+	scene.Update(0.5)
+	if next := scene.Button(ButtonPress{Button: LeftButtonId, Event: ButtonRelease}); next != sceneOpening {
+		t.Fatal("not started by a short push", next)
+	}
+	if game.leftAI != "easy" || game.rightAI != "" {
+		t.Fatal("computers after a short push", game.leftAI, game.rightAI)
+	}
+}
+
+// Helper assert method
+func Assert(actual, expected int, message string, t *testing.T) {
+	if actual != expected {
+		t.Fatal(message, actual, "vs expected", expected)
+	}
+}
+
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}